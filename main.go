@@ -1,19 +1,31 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -60,9 +72,49 @@ var (
 	helpStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#626262"))
 
+	warningStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F1FA8C"))
+
+	reminderStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F1FA8C")).
+			Bold(true).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#F1FA8C")).
+			Padding(0, 1)
+
 	docStyle = lipgloss.NewStyle().Padding(1, 2, 1, 2)
 )
 
+// icon is an emoji used in titles and messages, with the ASCII stand-in
+// printed instead when emoji are disabled. Both include trailing spacing.
+type icon struct {
+	emoji, plain string
+}
+
+var (
+	iconTimer   = icon{"⏱️  ", ""}
+	iconDone    = icon{"✅ ", "[ok] "}
+	iconNote    = icon{"📝 ", ""}
+	iconFolder  = icon{"🗂️  ", ""}
+	iconReport  = icon{"📊 ", ""}
+	iconHelp    = icon{"❓ ", ""}
+	iconProject = icon{"📁 ", ""}
+	iconNight   = icon{"🌙 ", "[closed] "}
+	iconTomato  = icon{"🍅 ", ""}
+	iconStreak  = icon{"🔥 ", ""}
+	iconWarning = icon{"⚠ ", "! "}
+)
+
+// emojiDisabled swaps every icon for its plain stand-in (disable_emoji / -no-emoji)
+var emojiDisabled bool
+
+func (i icon) String() string {
+	if emojiDisabled {
+		return i.plain
+	}
+	return i.emoji
+}
+
 // Data structures
 type ActivityType int
 
@@ -85,12 +137,49 @@ func (a ActivityType) String() string {
 	}
 }
 
+// parseActivityType parses "work", "break" or "ignored" (any case)
+func parseActivityType(s string) (ActivityType, bool) {
+	for _, t := range []ActivityType{Work, Break, Ignored} {
+		if strings.EqualFold(s, t.String()) {
+			return t, true
+		}
+	}
+	return Work, false
+}
+
+// explicitType returns the value for Entry.Type when t differs from what the
+// name's markers imply, or "" when the name already says it
+func (p nameParser) explicitType(name string, t ActivityType) string {
+	if p.parseName(name).Type == t {
+		return ""
+	}
+	return strings.ToLower(t.String())
+}
+
 type Entry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Name      string    `json:"name"`
-	Comment   string    `json:"comment,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Name      string            `json:"name"`
+	Comment   string            `json:"comment,omitempty"`
+	Action    string            `json:"action,omitempty"` // actionOpen/actionClose for parallel tasks, actionIn/actionOut for clocked ones, empty otherwise
+	Type      string            `json:"type,omitempty"`   // Explicit type overriding the name's marker: work, break or ignored
+	Meta      map[string]string `json:"meta,omitempty"`   // Free-form key/value metadata, e.g. ticket or location
+	Tags      []string          `json:"tags,omitempty"`   // +tags split out of the name, without the '+'
 }
 
+// Parallel task markers. Entries carrying an action sit outside the regular
+// sequential timeline: each open is paired with the next close of the same name.
+const (
+	actionOpen  = "open"
+	actionClose = "close"
+)
+
+// Clock-in/out markers. Like parallel tasks they are paired by name instead of
+// reading the duration off the next entry, but count as regular activities.
+const (
+	actionIn  = "in"
+	actionOut = "out"
+)
+
 type Activity struct {
 	Name     string
 	Start    time.Time
@@ -101,16 +190,186 @@ type Activity struct {
 	Task     string
 	Comment  string
 	IsCurrent bool
+	Parallel bool          // Built from an open/close pair rather than the timeline
+	Overlap  time.Duration // Part of Duration already counted by another activity
+	Merged   bool          // Coalesced from several entries by merge_consecutive
+	Meta     map[string]string
+	Tags     []string
+}
+
+// counted is the part of the activity that contributes to totals
+func (a Activity) counted() time.Duration {
+	return a.Duration - a.Overlap
 }
 
 type Config struct {
-	DataFile string `json:"data_file"`
-	Editor   string `json:"editor"`
+	DataFile     string `json:"data_file"`
+	Editor       string `json:"editor"`
+	RequireStart bool   `json:"require_start"`  // Only count activities that follow an explicit Start
+	WeekStart    string `json:"week_start"`     // First day of the week for "this week"/"last week"
+	DayStartHour int    `json:"day_start_hour"` // Today runs from this hour to the same hour tomorrow (0 = midnight)
+
+	AutoStartDay     bool `json:"auto_start_day"`     // Insert a Start before the first task of a day
+	AutoStartMinutes int  `json:"auto_start_minutes"` // How long before that task the Start goes (0 = midnight)
+	MinExtendMinutes int  `json:"min_extend_minutes"` // Refuse to extend entries younger than this without force
+
+	SplitDay           bool `json:"split_day"`            // Show morning/afternoon/evening work totals in reports
+	AfternoonStartHour int  `json:"afternoon_start_hour"` // Hour the afternoon bucket begins
+	EveningStartHour   int  `json:"evening_start_hour"`   // Hour the evening bucket begins
+
+	CalendarProject string `json:"calendar_project"` // Project prefix for tasks imported from a calendar
+
+	WeeklyGoalHours float64 `json:"weekly_goal_hours"` // Work hours aimed for per week (0 hides the readout)
+
+	StartupAction string `json:"startup_action"` // View the TUI opens in: main, report, add or resume-last
+
+	ParallelMode   bool   `json:"parallel_mode"`   // Allow named tasks to run alongside the timeline
+	ParallelTotals string `json:"parallel_totals"` // "once" counts overlapping time once, "per-task" counts it for each task
+
+	ReportShowTypes []string `json:"report_show_types"` // Activity types listed in the report table (empty = all)
+
+	AutoCloseAtHour   int  `json:"auto_close_at_hour"`  // Hour a day left open is closed at (0 = no detection)
+	AutoClose         bool `json:"auto_close"`          // Close days left open without asking when running CLI commands
+	OvernightGapHours int  `json:"overnight_gap_hours"` // Longest gap across midnight still read as one task (0 = never)
+
+	BillableProjects []string `json:"billable_projects"`  // Projects whose work is billable (empty = all work)
+	AutoLunchMinutes int      `json:"auto_lunch_minutes"` // Lunch deducted from billable time when none was logged
+
+	Profiles map[string]string `json:"profiles,omitempty"` // Named data files to switch between
+	Profile  string            `json:"profile,omitempty"`  // Active profile ("" or "default" = data_file)
+
+	DisableEmoji bool `json:"disable_emoji"` // Print plain ASCII instead of emoji in titles and messages
+
+	StandardWeeklyHours float64 `json:"standard_weekly_hours"` // Work per week beyond which it's overtime (0 = off)
+	StandardDailyHours  float64 `json:"standard_daily_hours"`  // Work per day beyond which it's overtime (0 = off)
+
+	QuickTasks []string          `json:"quick_tasks"` // Task names logged with one key (1-9) or tt -q N
+	Shortcuts  map[string]string `json:"shortcuts"`   // @alias typed in a task name -> the text it stands for
+
+	DropBelowMinutes int `json:"drop_below_minutes"` // Activities shorter than this don't count anywhere (0 = keep all)
+	RoundToMinutes   int `json:"round_to_minutes"`   // Reports round each activity to this increment (0 = exact)
+
+	MergeConsecutive bool `json:"merge_consecutive"` // Reports show back-to-back activities with the same name and type as one
+
+	IdleThresholdMinutes int `json:"idle_threshold_minutes"` // Offer to split gaps longer than this off a new task as idle time (0 = off)
+
+	MaxBreakMinutes int `json:"max_break_minutes"` // Reports flag breaks longer than this (0 = off)
+	LongTaskMinutes int `json:"long_task_minutes"` // The TUI notifies once when nothing was logged for this long (0 = off)
+
+	AuditLog bool `json:"audit_log"` // Append a line to audit.log for every change to the data
+
+	IncludeIgnored bool `json:"include_ignored"` // Show an Elapsed line adding ignored time to the total (i toggles it in the TUI)
+
+	TagProjectMap map[string]string `json:"tag_project_map,omitempty"` // Project implied by a #tag when the name has none
+
+	ReportColumns []ReportColumn `json:"report_columns,omitempty"` // Columns of the TUI report table, in order (empty = default)
+
+	Sprints []Sprint `json:"sprints,omitempty"` // Named periods reported on with -sprint
+
+	MaxWorkdayHours float64 `json:"max_workday_hours"` // Day span after which a gentle break nudge shows (0 = off)
+
+	BellOnComplete bool `json:"bell_on_complete"` // Ring the terminal bell when a task is logged
+
+	DailyTargetHours float64 `json:"daily_target_hours"` // Work aimed for per day, shown as progress and projected by -pace (0 = off)
+	SkipWeekends     bool    `json:"skip_weekends"`      // Saturdays and Sundays neither extend nor break the target streak
+
+	BreakMarker   string `json:"break_marker"`   // Marks a task name as a break, at its end or start
+	IgnoredMarker string `json:"ignored_marker"` // Marks a task name as ignored, at its end or start
+	ProjectDepth  int    `json:"project_depth"`  // Colon-separated levels that make up the project, e.g. 2 for "Client: Project: Task"
+
+	HourlyRate   float64            `json:"hourly_rate"`             // Rate for billable work (0 = no earnings shown)
+	ProjectRates map[string]float64 `json:"project_rates,omitempty"` // Rates for projects billed differently
+	Currency     string             `json:"currency"`                // Written before amounts
+
+	TimeFormat     string `json:"time_format"`     // Clock times: "24h", "12h" or a Go layout such as "3:04pm"
+	DurationFormat string `json:"duration_format"` // Durations: "hm" (3h05), "colon" (3:05) or "decimal" (3.08h)
+
+	PomodoroWorkMinutes  int `json:"pomodoro_work_minutes"`  // Length of a pomodoro's focus interval
+	PomodoroBreakMinutes int `json:"pomodoro_break_minutes"` // Length of the break after it
+
+	BackupRetentionDays int `json:"backup_retention_days"` // Daily backups older than this are deleted (0 = keep all)
+	ArchiveAfterDays    int `json:"archive_after_days"`    // tt -archive moves entries older than this into monthly archive files
+
+	ConfigVersion int `json:"config_version"` // Format of config.json, so old defaults are migrated once
+}
+
+// configVersion is the current config_version. Version 1 stopped writing
+// "vi" as the default editor, so $EDITOR applies to an editor left unset.
+const configVersion = 1
+
+// Sprint is a named period of whole days, both ends included
+type Sprint struct {
+	Name  string `json:"name"`
+	Start string `json:"start"` // YYYY-MM-DD
+	End   string `json:"end"`   // YYYY-MM-DD, the last day of the sprint
+}
+
+// ReportColumn is one column of the TUI report table
+type ReportColumn struct {
+	Name  string      `json:"name"`  // time, duration, activity, type, comment or project
+	Width columnWidth `json:"width"` // Characters wide, or "auto" to share what's left
+}
+
+// columnWidth is a fixed column width, 0 meaning "auto" and -1 a width that
+// couldn't be read, which is laid out like "auto"
+type columnWidth int
+
+func (w *columnWidth) UnmarshalJSON(data []byte) error {
+	var n int
+	var s string
+	switch {
+	case json.Unmarshal(data, &n) == nil && n > 0:
+		*w = columnWidth(n)
+	case json.Unmarshal(data, &s) == nil && s == "auto":
+		*w = 0
+	default:
+		*w = -1 // Reported by checkReportColumns rather than failing the whole config
+	}
+	return nil
+}
+
+func (w columnWidth) MarshalJSON() ([]byte, error) {
+	if w <= 0 {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(int(w))
+}
+
+// DayInfo is metadata attached to a whole day rather than to an entry
+type DayInfo struct {
+	Reflection string `json:"reflection,omitempty"`
+}
+
+// DayStats holds the work/break totals for a set of activities
+type DayStats struct {
+	WorkTime    time.Duration
+	BreakTime   time.Duration
+	TotalTime   time.Duration // Work and breaks
+	IgnoredTime time.Duration // Kept out of every other total
+}
+
+// Elapsed is the total with ignored time added back in
+func (s DayStats) Elapsed() time.Duration {
+	return s.TotalTime + s.IgnoredTime
 }
 
 type TimeTracker struct {
 	entries []Entry
 	config  Config
+	days    map[string]DayInfo // Keyed by dayKey
+	
+	defaultDataFile string // data_file as configured, before the active profile swaps in its own
+	undone          int    // Entries removed by undoLast this session
+	
+	archived       []Entry         // Entries read back from archive files for reports, never saved to the data file
+	archivedMonths map[string]bool // Archive months already read, by "2006-01"
+	dayWork        map[string]time.Duration // Work per day by its start, until the entries change
+	intervals      map[string]pairedIntervals // pairIntervals by open action, until the entries change
+	dayActivities  map[string][]Activity      // getDayActivities by dayKey, until the entries change
+	
+	corrupt          error       // Why the data file couldn't be read; it isn't saved over until confirmed
+	recoveredFrom    string      // Backup loaded in place of a data file that couldn't be read
+	confirmOverwrite func() bool // Asked before saving over a data file that couldn't be read
 }
 
 // Views
@@ -121,6 +380,10 @@ const (
 	addTaskView
 	reportView
 	helpView
+	reflectView
+	profilesView
+	pomodoroView
+	noteView
 )
 
 // Key mappings
@@ -137,6 +400,33 @@ type keyMap struct {
 	Report   key.Binding
 	Hello    key.Binding
 	Stretch  key.Binding
+	Expand   key.Binding
+	Save     key.Binding
+	CycleType key.Binding
+	Reflect  key.Binding
+	FilterTypes key.Binding
+	Yes      key.Binding
+	No       key.Binding
+	Profiles key.Binding
+	QuickTask key.Binding
+	Dismiss  key.Binding
+	SwitchField key.Binding
+	Span     key.Binding
+	Undo     key.Binding
+	Edit     key.Binding
+	Pomodoro key.Binding
+	Cancel   key.Binding
+	Search   key.Binding
+	Elapsed  key.Binding
+	Note     key.Binding
+	Heatmap  key.Binding
+	Suggest  key.Binding
+	Split    key.Binding
+	Sort     key.Binding
+	Reverse  key.Binding
+	Interrupt key.Binding
+	EditFile key.Binding
+	DurationMode key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -146,7 +436,7 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Left, k.Right},
-		{k.AddTask, k.Report, k.Hello, k.Stretch},
+		{k.AddTask, k.Report, k.Hello, k.Stretch, k.Reflect},
 		{k.Enter, k.Back, k.Help, k.Quit},
 	}
 }
@@ -200,6 +490,114 @@ var keys = keyMap{
 		key.WithKeys("x"),
 		key.WithHelp("x", "extend last task"),
 	),
+	Expand: key.NewBinding(
+		key.WithKeys("ctrl+e"),
+		key.WithHelp("ctrl+e", "multi-line comment"),
+	),
+	Save: key.NewBinding(
+		key.WithKeys("ctrl+s"),
+		key.WithHelp("ctrl+s", "save"),
+	),
+	CycleType: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("ctrl+t", "change task type"),
+	),
+	Reflect: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "reflect on the day"),
+	),
+	FilterTypes: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "filter report by type"),
+	),
+	Yes: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "yes"),
+	),
+	No: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "no"),
+	),
+	Profiles: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "switch profile"),
+	),
+	QuickTask: key.NewBinding(
+		key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		key.WithHelp("1-9", "log a quick task"),
+	),
+	Dismiss: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "dismiss the long day note"),
+	),
+	SwitchField: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "set when the task finished"),
+	),
+	Span: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "report a day, week or month"),
+	),
+	Undo: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "remove the last entry"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit the selected entry"),
+	),
+	Pomodoro: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "pomodoro timer"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "cancel the pomodoro"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search the report"),
+	),
+	Elapsed: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "show elapsed time with ignored"),
+	),
+	Note: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "add a note to the last task"),
+	),
+	Heatmap: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "show work by hour of day"),
+	),
+	Suggest: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "use the next git commit as the task"),
+	),
+	Split: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "split the selected entry in two"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "sort by the next column"),
+	),
+	Reverse: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "reverse the sort order"),
+	),
+	Interrupt: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	EditFile: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "open the data file in your editor"),
+	),
+	DurationMode: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "show durations as time, share or bar"),
+	),
 }
 
 // Model
@@ -213,6 +611,10 @@ type model struct {
 	// Components
 	help       help.Model
 	taskInput  textinput.Model
+	timeInput  textinput.Model
+	commentArea textarea.Model
+	reflectInput textinput.Model
+	noteInput  textinput.Model
 	viewport   viewport.Model
 	table      table.Model
 	
@@ -224,20 +626,116 @@ type model struct {
 	taskName    string
 	taskComment string
 	inputMode   int // 0 = name, 1 = comment
+	multiline   bool // Comment is being edited in commentArea
+	taskType    ActivityType // Type chosen for the task, defaulting to its markers
+	taskTime    time.Time    // When the task finished (zero = now)
+	editingTime bool         // The name step has timeInput focused
+	gitCommits  []gitCommit  // Commits since the last entry, offered as task names
+	gitCursor   int          // Index of the commit picked with ctrl+g, -1 for none
+	quitPending bool         // ctrl+c was pressed once with a draft in the form
+	
+	// Report
+	reportTypes map[ActivityType]bool // Types listed in the report table
+	reportDay   time.Time             // Day shown in the report view, or a day in the week/month shown
+	reportSpan  string                // "day" (or ""), "week" or "month"
+	reportRows  []Activity            // Activities behind the report table rows
+	reportSearch string               // Only rows whose name or comment contain this
+	reportHeatmap bool                // The summary box shows work by hour of day instead
+	reportSort   string               // Column the table is sorted by, "" for the order logged
+	reportDesc   bool                 // Sort reportSort from largest to smallest
+	durationMode string               // Duration column shows "" time, "percent" of the total or a "bar"
+	searching    bool                 // searchInput has focus
+	searchInput  textinput.Model
+	
+	// Editing an entry from the report table
+	editing   bool
+	editIndex int    // Index of the entry in tracker.entries
+	editField int    // 0 = name, 1 = comment
+	editName  string // Name typed before moving on to the comment
+	editInput textinput.Model
+	splitting  bool      // editInput holds the minutes after splitStart to split the entry at
+	splitStart time.Time // Start of the activity being split
+	
+	// Action asked about in message, run when answered with y
+	confirm     func() error
+	confirmDone string // Message shown once confirm succeeds
+	
+	// Profile picker
+	profiles      []profile
+	profileCursor int
+	
+	longDayDismissed string // dayKey of the day whose long-day note was dismissed
+	longTaskNotified time.Time // Timestamp of the entry after which a long-running task was notified
+	configErr        error  // Why config.json couldn't be read, shown as a banner
+	ticking          bool   // A tick is scheduled
+	bells            int    // Rings owed for a task just logged, sounded by Update
+	
+	// Pomodoro timer
+	pomoInput textinput.Model
+	pomoTask  string    // What the running pomodoro is for
+	pomoPhase string    // "work", "break", or "" when no pomodoro runs
+	pomoEnd   time.Time // When the current phase is over
+	pomoDone  int       // Pomodoros completed this session
 }
 
 func initialModel() model {
 	tracker := &TimeTracker{}
-	tracker.loadConfig()
+	configErr := tracker.loadConfig()
 	tracker.loadEntries()
+	tracker.loadDays()
+	emojiDisabled = emojiDisabled || tracker.config.DisableEmoji
 
 	// Initialize task input
 	ti := textinput.New()
-	ti.Placeholder = "Enter task name (e.g., 'Education: CKA Labs' or 'Lunch **')"
+	ti.Placeholder = "Enter task name (e.g., 'Education: CKA Labs' or 'Lunch " + tracker.config.BreakMarker + "')"
 	ti.Focus()
 	ti.CharLimit = 156
 	ti.Width = 50
 
+	// Initialize finish time input
+	tmi := textinput.New()
+	tmi.Prompt = ""
+	tmi.Placeholder = "now (or HH:MM)"
+	tmi.CharLimit = 25
+	tmi.Width = 25
+
+	// Initialize entry edit input
+	ei := textinput.New()
+	ei.CharLimit = 156
+	ei.Width = 50
+
+	// Initialize reflection input
+	ri := textinput.New()
+	ri.Placeholder = "How did today go?"
+	ri.CharLimit = 500
+	ri.Width = 60
+
+	// Initialize note input
+	ni := textinput.New()
+	ni.Placeholder = "What else happened?"
+	ni.CharLimit = 500
+	ni.Width = 60
+
+	// Initialize report search input
+	si := textinput.New()
+	si.Prompt = "/ "
+	si.Placeholder = "name or comment"
+	si.CharLimit = 100
+	si.Width = 40
+
+	// Initialize pomodoro task input
+	pi := textinput.New()
+	pi.Placeholder = "Pomodoro"
+	pi.CharLimit = 156
+	pi.Width = 50
+
+	// Initialize multi-line comment editor
+	ta := textarea.New()
+	ta.Placeholder = "Comment (Enter for a new line)"
+	ta.ShowLineNumbers = false
+	ta.SetWidth(50)
+	ta.SetHeight(5)
+
 	// Initialize help
 	h := help.New()
 	h.Width = 50
@@ -250,12 +748,7 @@ func initialModel() model {
 		PaddingRight(2)
 
 	// Initialize table
-	columns := []table.Column{
-		{Title: "Time", Width: 10},
-		{Title: "Duration", Width: 12},
-		{Title: "Activity", Width: 40},
-		{Title: "Type", Width: 8},
-	}
+	columns, _ := tracker.config.tableColumns(80)
 
 	t := table.New(
 		table.WithColumns(columns),
@@ -275,22 +768,306 @@ func initialModel() model {
 		Bold(false)
 	t.SetStyles(s)
 
-	return model{
+	m := model{
 		tracker:     tracker,
 		currentView: mainView,
 		help:        h,
 		taskInput:   ti,
+		timeInput:   tmi,
+		editInput:   ei,
+		commentArea: ta,
+		reflectInput: ri,
+		noteInput:   ni,
+		pomoInput:   pi,
+		searchInput: si,
 		viewport:    vp,
 		table:       t,
 		inputMode:   0,
+		gitCursor:   -1,
+		reportTypes: tracker.config.reportTypes(),
+		configErr:   configErr,
+	}
+	m.applyStartupAction(tracker.config.StartupAction)
+	
+	// A damaged data file comes first: nothing is saved until it's settled.
+	// Offer to close a day that was never closed before it skews the next one.
+	if tracker.corrupt != nil {
+		m.currentView = mainView
+		m.ask("Save over the data file that couldn't be read? The damaged file is kept next to it.", "Changes will be saved", func() error {
+			tracker.confirmOverwrite = func() bool { return true }
+			return nil
+		})
+	} else if closures := tracker.staleClosures(time.Now()); len(closures) > 0 {
+		m.currentView = mainView
+		m.ask(strings.Join(describeClosures(tracker.config.display(), closures), "; ")+". Close it?", "Day closed!", func() error {
+			return tracker.closeStale(closures)
+		})
+	} else {
+		m.restoreDraft()
+	}
+	return m
+}
+
+// defaultReportColumns is the report table layout used when report_columns is unset
+var defaultReportColumns = []ReportColumn{
+	{Name: "time", Width: 10},
+	{Name: "duration", Width: 12},
+	{Name: "activity", Width: 40},
+	{Name: "type", Width: 8},
+}
+
+// reportColumnTitles holds the header of every column report_columns may list
+var reportColumnTitles = map[string]string{
+	"time":     "Time",
+	"duration": "Duration",
+	"activity": "Activity",
+	"type":     "Type",
+	"comment":  "Comment",
+	"project":  "Project",
+}
+
+// reportColumns is the configured report table layout. By default the time
+// column widens to fit clock times longer than "15:04".
+func (c Config) reportColumns() []ReportColumn {
+	if len(c.ReportColumns) > 0 {
+		return c.ReportColumns
+	}
+	columns := append([]ReportColumn(nil), defaultReportColumns...)
+	if span := len(c.display().clockSpan(time.Time{}, time.Time{})); span > len("15:04-15:04") {
+		columns[0].Width = columnWidth(span)
+	}
+	return columns
+}
+
+// checkReportColumns reports report_columns entries that tableColumns
+// leaves out or lays out as "auto": unknown names and unreadable widths
+func (c Config) checkReportColumns() error {
+	var problems []string
+	for _, column := range c.ReportColumns {
+		if _, ok := reportColumnTitles[column.Name]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown column %q is left out", column.Name))
+		} else if column.Width < 0 {
+			problems = append(problems, fmt.Sprintf("the width of %q must be a positive number or \"auto\"", column.Name))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("report_columns: %s (columns are time, duration, activity, type, comment and project)", strings.Join(problems, "; "))
+}
+
+// tableColumns builds the report table columns for a terminal width wide.
+// Auto columns share whatever the fixed ones leave, but never shrink below
+// 8 characters. It also returns the width the table needs, which is more
+// than width when the columns don't fit.
+func (c Config) tableColumns(width int) ([]table.Column, int) {
+	const padding = 2 // The table pads each cell with a space on either side
+	const minAuto = 8
+	
+	fixed, autos := 0, 0
+	for _, column := range c.reportColumns() {
+		if _, ok := reportColumnTitles[column.Name]; !ok {
+			continue
+		}
+		if column.Width > 0 {
+			fixed += int(column.Width) + padding
+		} else {
+			autos++
+			fixed += padding
+		}
+	}
+	auto := minAuto
+	if autos > 0 && (width-fixed)/autos > minAuto {
+		auto = (width - fixed) / autos
+	}
+	
+	var columns []table.Column
+	needed := 0
+	for _, column := range c.reportColumns() {
+		title, ok := reportColumnTitles[column.Name]
+		if !ok {
+			continue
+		}
+		w := int(column.Width)
+		if w <= 0 {
+			w = auto
+		}
+		columns = append(columns, table.Column{Title: title, Width: w})
+		needed += w + padding
+	}
+	return columns, needed
+}
+
+// columnCell is what the named report column shows for an activity; ok is
+// false for names that aren't a column
+func columnCell(show displayFormats, name string, activity Activity) (string, bool) {
+	switch name {
+	case "time":
+		return show.clockSpan(activity.Start, activity.End), true
+	case "duration":
+		return show.duration(activity.Duration), true
+	case "activity":
+		return activity.Name, true
+	case "type":
+		return activity.Type.String(), true
+	case "comment":
+		return strings.ReplaceAll(activity.Comment, "\n", " "), true
+	case "project":
+		return activity.Project, true
+	}
+	return "", false
+}
+
+// nextSortColumn is the report column to sort by after current, cycling
+// through the shown columns and back to the order logged ("")
+func (c Config) nextSortColumn(current string) string {
+	var names []string
+	for _, column := range c.reportColumns() {
+		if _, ok := reportColumnTitles[column.Name]; ok {
+			names = append(names, column.Name)
+		}
+	}
+	for i, name := range names {
+		if name == current {
+			if i+1 < len(names) {
+				return names[i+1]
+			}
+			return ""
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// sortActivities orders activities by the named report column, keeping the
+// order logged for ties. An empty column keeps the order logged throughout.
+func sortActivities(show displayFormats, activities []Activity, column string, desc bool) {
+	var less func(a, b Activity) bool
+	switch column {
+	case "time":
+		less = func(a, b Activity) bool { return a.Start.Before(b.Start) }
+	case "duration":
+		less = func(a, b Activity) bool { return a.Duration < b.Duration }
+	case "type":
+		less = func(a, b Activity) bool { return a.Type < b.Type }
+	case "activity", "comment", "project":
+		less = func(a, b Activity) bool {
+			x, _ := columnCell(show, column, a)
+			y, _ := columnCell(show, column, b)
+			return strings.ToLower(x) < strings.ToLower(y)
+		}
+	default:
+		if desc {
+			for i, j := 0, len(activities)-1; i < j; i, j = i+1, j-1 {
+				activities[i], activities[j] = activities[j], activities[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(activities, func(i, j int) bool {
+		if desc {
+			return less(activities[j], activities[i])
+		}
+		return less(activities[i], activities[j])
+	})
+}
+
+// breakOverrun is how far a break ran past max_break_minutes, or 0
+func (c Config) breakOverrun(activity Activity) time.Duration {
+	limit := time.Duration(c.MaxBreakMinutes) * time.Minute
+	if activity.Type != Break || limit <= 0 || activity.Duration <= limit {
+		return 0
+	}
+	return activity.Duration - limit
+}
+
+// overrunNote flags an activity that ran over its limit
+func overrunNote(show displayFormats, over time.Duration) string {
+	return iconWarning.String() + "over by " + show.duration(over)
+}
+
+// applyStartupAction puts the model in the view configured to open first:
+// "main" (default), "report", "add", or "resume-last" which opens the add
+// form pre-filled with the last task's name
+func (m *model) applyStartupAction(action string) {
+	switch action {
+	case "report":
+		m.currentView = reportView
+		m.updateReportData()
+	case "add":
+		m.currentView = addTaskView
+	case "resume-last":
+		m.currentView = addTaskView
+		for i := len(m.tracker.entries) - 1; i >= 0; i-- {
+			if entry := m.tracker.entries[i]; entry.Action == "" && entry.Name != "Start" && entry.Name != "Stop" {
+				m.taskInput.SetValue(entry.Name)
+				break
+			}
+		}
+	default:
+		m.currentView = mainView
+	}
+}
+
+// tickMsg re-renders time-dependent readouts such as "X ago" and billable
+// time, and advances the pomodoro timer. Ticks only run while the main view
+// is shown or a pomodoro runs.
+type tickMsg time.Time
+
+// gitCommitsMsg carries the commits offered as task names in the add form
+type gitCommitsMsg []gitCommit
+
+// loadGitCommits looks up the commits made since since off the UI loop.
+// Outside a git repository there are simply no suggestions.
+func loadGitCommits(since time.Time) tea.Cmd {
+	return func() tea.Msg {
+		commits, _ := gitCommits(".", since)
+		return gitCommitsMsg(commits)
 	}
 }
 
+// editorFinishedMsg reports that the editor opened on the data file exited
+type editorFinishedMsg struct{ err error }
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
 func (m model) Init() tea.Cmd {
 	return tea.EnterAltScreen
 }
 
+// Update handles msg and, once the main view is shown again or a pomodoro
+// starts, resumes the ticks that stopped while they weren't needed
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	m, ok := next.(model)
+	if !ok {
+		return next, cmd
+	}
+	if m.bells > 0 {
+		cmd = tea.Batch(cmd, bell(m.bells))
+		m.bells = 0
+	}
+	if !m.needsTicks() || m.ticking {
+		return m, cmd
+	}
+	m.ticking = true
+	return m, tea.Batch(cmd, tick())
+}
+
+// needsTicks is whether something is counting down or being watched: the
+// main view's clock, a pomodoro, or the long_task_minutes notification,
+// which should still come while a form or report is open
+func (m model) needsTicks() bool {
+	return m.currentView == mainView || m.pomoPhase != "" || m.tracker.config.LongTaskMinutes > 0
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
@@ -302,6 +1079,43 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.Height = msg.Height - 10
 		m.help.Width = msg.Width
 		m.ready = true
+		
+		// Auto-width columns follow the terminal; fixed ones may not fit it
+		columns, needed := m.tracker.config.tableColumns(msg.Width)
+		m.table.SetRows(nil)
+		m.table.SetColumns(columns)
+		m.updateReportData()
+		if needed > msg.Width && m.confirm == nil {
+			m.message = fmt.Sprintf("The report columns need %d characters but the terminal is %d wide", needed, msg.Width)
+			m.messageType = "warning"
+		}
+
+	case gitCommitsMsg:
+		// The form may have been left or reopened in the meantime
+		if m.currentView == addTaskView && m.gitCursor < 0 {
+			m.gitCommits = msg
+		}
+		return m, nil
+
+	case editorFinishedMsg:
+		if err := m.tracker.finishEdit(msg.err); err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+		} else {
+			m.message = fmt.Sprintf("Reloaded %d entries from %s", len(m.tracker.entries), m.tracker.config.DataFile)
+			m.messageType = "success"
+		}
+		m.updateReportData()
+		return m, nil
+
+	case tickMsg:
+		m.advancePomodoro(time.Time(msg))
+		notify := m.checkLongTask(time.Time(msg))
+		if !m.needsTicks() {
+			m.ticking = false
+			return m, notify
+		}
+		return m, tea.Batch(tick(), notify)
 
 	case tea.KeyMsg:
 		switch m.currentView {
@@ -313,6 +1127,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateReportView(msg)
 		case helpView:
 			return m.updateHelpView(msg)
+		case reflectView:
+			return m.updateReflectView(msg)
+		case profilesView:
+			return m.updateProfilesView(msg)
+		case pomodoroView:
+			return m.updatePomodoroView(msg)
+		case noteView:
+			return m.updateNoteView(msg)
 		}
 	}
 
@@ -332,6 +1154,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateMainView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	show := m.tracker.config.display()
+	// Any answer other than yes dismisses a pending question
+	if confirm := m.confirm; confirm != nil {
+		m.confirm = nil
+		m.message = ""
+		switch {
+		case key.Matches(msg, keys.Yes):
+			if err := confirm(); err != nil {
+				m.message = fmt.Sprintf("Error: %v", err)
+				m.messageType = "error"
+			} else {
+				m.message = m.confirmDone
+				m.messageType = "success"
+			}
+			return m, nil
+		case key.Matches(msg, keys.No):
+			return m, nil
+		}
+	}
+	
 	switch {
 	case key.Matches(msg, keys.Quit):
 		return m, tea.Quit
@@ -340,694 +1182,6724 @@ func (m model) updateMainView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.taskInput.SetValue("")
 		m.taskInput.Focus()
 		m.inputMode = 0
+		m.gitCommits = nil
+		m.gitCursor = -1
 		m.message = ""
 		m.messageType = ""
+		return m, loadGitCommits(m.tracker.gitSince(time.Now()))
 	case key.Matches(msg, keys.Report):
 		m.currentView = reportView
+		m.reportDay = time.Time{}
+		m.reportSpan = "day"
+		m.message = ""
 		m.updateReportData()
 	case key.Matches(msg, keys.Hello):
-		m.tracker.addStart()
+		if err := m.tracker.addStart(time.Now(), false); err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			break
+		}
 		m.message = "Day started!"
 		m.messageType = "success"
 	case key.Matches(msg, keys.Stretch):
-		err := m.tracker.extend()
+		entry, from, err := m.tracker.extendEntry(false, time.Now())
 		if err != nil {
 			m.message = fmt.Sprintf("Error: %v", err)
 			m.messageType = "error"
 		} else {
-			m.message = "Task extended to current time!"
-			m.messageType = "success"
+			m.ask(extendPreview(show, entry, from), "Task extended to current time!", func() error {
+				return m.tracker.extend(false)
+			})
 		}
 	case key.Matches(msg, keys.Help):
 		m.currentView = helpView
-	}
-	return m, nil
-}
-
-func (m model) updateAddTaskView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-	
-	switch {
-	case key.Matches(msg, keys.Back):
-		m.currentView = mainView
-		m.taskInput.Blur()
+	case key.Matches(msg, keys.Pomodoro):
+		m.currentView = pomodoroView
 		m.message = ""
-		return m, nil
-	case key.Matches(msg, keys.Enter):
-		if m.inputMode == 0 {
-			// Save task name and move to comment
-			m.taskName = m.taskInput.Value()
-			if m.taskName == "" {
-				m.message = "Task name cannot be empty"
-				m.messageType = "error"
-				return m, nil
-			}
-			m.inputMode = 1
-			m.taskInput.SetValue("")
-			m.taskInput.Placeholder = "Optional comment (press Enter to skip)"
-			m.taskInput.Focus()
-		} else {
-			// Save comment and add task
-			m.taskComment = m.taskInput.Value()
-			
-			entry := Entry{
-				Timestamp: time.Now(),
-				Name:      m.taskName,
-				Comment:   m.taskComment,
-			}
-			
-			err := m.tracker.addEntry(entry)
-			if err != nil {
-				m.message = fmt.Sprintf("Error adding task: %v", err)
-				m.messageType = "error"
-			} else {
-				// Calculate duration from last entry
-				var durationMsg string
-				if len(m.tracker.entries) > 1 {
-					lastEntry := m.tracker.entries[len(m.tracker.entries)-2] // Previous entry before the one we just added
-					duration := entry.Timestamp.Sub(lastEntry.Timestamp)
-					durationMsg = fmt.Sprintf(" (%s)", formatDuration(duration))
-				}
-				m.message = fmt.Sprintf("Task completed: %s%s", m.taskName, durationMsg)
-				m.messageType = "success"
-				m.currentView = mainView
-				m.taskInput.Blur()
+		if m.pomoPhase == "" {
+			return m, m.pomoInput.Focus()
+		}
+	case key.Matches(msg, keys.Reflect):
+		m.currentView = reflectView
+		m.reflectInput.SetValue(m.tracker.days[dayKey(m.tracker.todayStart(time.Now()))].Reflection)
+		m.message = ""
+		return m, m.reflectInput.Focus()
+	case key.Matches(msg, keys.Note):
+		if _, err := m.tracker.lastTaskIndex(); err != nil {
+			m.message = fmt.Sprintf("Cannot add a note: %v", err)
+			m.messageType = "error"
+			break
+		}
+		m.currentView = noteView
+		m.noteInput.SetValue("")
+		m.message = ""
+		return m, m.noteInput.Focus()
+	case key.Matches(msg, keys.QuickTask):
+		name, err := m.tracker.quickTask(int(msg.String()[0] - '0'))
+		if err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			break
+		}
+		m.taskName = name
+		m.taskType = m.tracker.config.parser().parseName(name).Type
+		m.completeTask()
+	case key.Matches(msg, keys.Undo):
+		last, ok := m.tracker.latestEntry()
+		if !ok {
+			m.message = "Nothing to undo: there are no entries"
+			m.messageType = "info"
+			break
+		}
+		done := fmt.Sprintf("Removed '%s' (%s)", last.Name, last.Timestamp.Format("Mon ")+show.clock(last.Timestamp))
+		if n := m.tracker.undone + 1; n > 1 {
+			done += fmt.Sprintf(", %d entries undone this session", n)
+		}
+		m.ask(fmt.Sprintf("Remove '%s' logged %s?", last.Name, last.Timestamp.Format("Mon ")+show.clock(last.Timestamp)), done, func() error {
+			_, err := m.tracker.undoLast()
+			return err
+		})
+	case key.Matches(msg, keys.Dismiss):
+		m.longDayDismissed = dayKey(m.tracker.todayStart(time.Now()))
+	case key.Matches(msg, keys.Elapsed):
+		m.tracker.config.IncludeIgnored = !m.tracker.config.IncludeIgnored
+	case key.Matches(msg, keys.EditFile):
+		cmd, err := m.tracker.editDataFile()
+		if err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return editorFinishedMsg{err}
+		})
+	case key.Matches(msg, keys.Profiles):
+		m.currentView = profilesView
+		m.profiles = m.tracker.profiles()
+		m.profileCursor = 0
+		for i, p := range m.profiles {
+			if p.Active {
+				m.profileCursor = i
 			}
-			
-			// Reset form
-			m.taskName = ""
-			m.taskComment = ""
-			m.inputMode = 0
-			m.taskInput.Placeholder = "Enter task name (e.g., 'Education: CKA Labs' or 'Lunch **')"
 		}
-		return m, nil
-	default:
-		// Let the text input handle other keys
-		m.taskInput, cmd = m.taskInput.Update(msg)
-		return m, cmd
+		m.message = ""
 	}
+	return m, nil
 }
 
-func (m model) updateReportView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// ask shows question in the main view and runs action if it's answered with y
+func (m *model) ask(question, done string, action func() error) {
+	m.message = question + " (y/n)"
+	m.messageType = "warning"
+	m.confirm = action
+	m.confirmDone = done
+}
+
+func (m model) updateProfilesView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, keys.Back):
 		m.currentView = mainView
 	case key.Matches(msg, keys.Quit):
 		return m, tea.Quit
+	case key.Matches(msg, keys.Up):
+		if m.profileCursor > 0 {
+			m.profileCursor--
+		}
+	case key.Matches(msg, keys.Down):
+		if m.profileCursor < len(m.profiles)-1 {
+			m.profileCursor++
+		}
+	case key.Matches(msg, keys.Enter):
+		name := m.profiles[m.profileCursor].Name
+		if err := m.tracker.switchProfile(name); err != nil {
+			m.message = fmt.Sprintf("Error switching profile: %v", err)
+			m.messageType = "error"
+		} else {
+			m.message = fmt.Sprintf("Switched to profile '%s'", name)
+			m.messageType = "success"
+		}
+		m.currentView = mainView
 	}
 	return m, nil
 }
 
-func (m model) updateHelpView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+func (m model) updatePomodoroView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	
 	switch {
-	case key.Matches(msg, keys.Back), key.Matches(msg, keys.Help):
+	case key.Matches(msg, keys.Back):
 		m.currentView = mainView
-	case key.Matches(msg, keys.Quit):
-		return m, tea.Quit
+		m.pomoInput.Blur()
+	case m.pomoPhase != "" && key.Matches(msg, keys.Cancel):
+		m.message = "Pomodoro cancelled, nothing logged"
+		m.messageType = "info"
+		m.pomoPhase = ""
+		return m, m.pomoInput.Focus()
+	case m.pomoPhase == "" && key.Matches(msg, keys.Enter):
+		m.pomoTask = strings.TrimSpace(m.pomoInput.Value())
+		if m.pomoTask == "" {
+			m.pomoTask = m.pomoInput.Placeholder
+		}
+		m.pomoPhase = "work"
+		m.pomoEnd = time.Now().Add(m.tracker.config.pomodoroWork())
+		m.message = ""
+		m.pomoInput.Blur()
+	case m.pomoPhase == "":
+		m.pomoInput, cmd = m.pomoInput.Update(msg)
+		return m, cmd
 	}
 	return m, nil
 }
 
-func (m *model) updateReportData() {
-	activities := m.tracker.getTodaysActivities()
-	
-	rows := []table.Row{}
-	for _, activity := range activities {
-		timeStr := activity.Start.Format("15:04") + "-" + activity.End.Format("15:04")
-		durationStr := formatDuration(activity.Duration)
-		activityName := activity.Name
-		
-		rows = append(rows, table.Row{
-			timeStr,
-			durationStr,
-			activityName,
-			activity.Type.String(),
-		})
+// pomodoroWork and pomodoroBreak are the configured interval lengths, at
+// least a minute each
+func (c Config) pomodoroWork() time.Duration {
+	return time.Duration(max(c.PomodoroWorkMinutes, 1)) * time.Minute
+}
+
+func (c Config) pomodoroBreak() time.Duration {
+	return time.Duration(max(c.PomodoroBreakMinutes, 1)) * time.Minute
+}
+
+// advancePomodoro moves a running pomodoro on once its phase is over. The
+// focus interval is logged as the pomodoro's task and the break after it as
+// a break, each at the moment it ended.
+func (m *model) advancePomodoro(now time.Time) {
+	if m.pomoPhase == "" || now.Before(m.pomoEnd) {
+		return
 	}
 	
-	m.table.SetRows(rows)
+	var entry Entry
+	if m.pomoPhase == "work" {
+		entry = Entry{Timestamp: m.pomoEnd, Name: m.pomoTask}
+	} else {
+		entry = Entry{Timestamp: m.pomoEnd, Name: "Break", Type: m.tracker.config.parser().explicitType("Break", Break)}
+	}
+	bells, err := m.tracker.logTask(entry)
+	if err != nil {
+		m.message = fmt.Sprintf("Error logging pomodoro: %v", err)
+		m.messageType = "error"
+		m.pomoPhase = ""
+		return
+	}
+	m.bells = bells
 	
-	// Generate summary for viewport
-	summary := m.tracker.generateTodaysSummary()
-	m.viewport.SetContent(summary)
+	if m.pomoPhase == "work" {
+		m.pomoDone++
+		m.message = fmt.Sprintf("Pomodoro %d done: %s. Take a %d-minute break!", m.pomoDone, m.pomoTask,
+			int(m.tracker.config.pomodoroBreak().Minutes()))
+		m.pomoPhase = "break"
+		m.pomoEnd = m.pomoEnd.Add(m.tracker.config.pomodoroBreak())
+	} else {
+		m.message = "Break over. Press o and Enter for the next pomodoro."
+		m.pomoPhase = ""
+	}
+	m.messageType = "success"
 }
 
-func (m model) View() string {
-	if !m.ready {
-		return "\n  Initializing..."
+// checkLongTask sends a desktop notification the first time the task
+// running since the last entry goes past long_task_minutes
+func (m *model) checkLongTask(now time.Time) tea.Cmd {
+	show := m.tracker.config.display()
+	since, elapsed, long := m.tracker.longTask(now)
+	if !long || since.Equal(m.longTaskNotified) {
+		return nil
 	}
+	m.longTaskNotified = since
+	body := fmt.Sprintf("Nothing logged for %s, since %s.", show.span(elapsed), show.clock(since))
+	return func() tea.Msg {
+		notify("Time Tracker", body) // The banner in the main view still shows when this fails
+		return nil
+	}
+}
 
-	switch m.currentView {
-	case mainView:
-		return m.mainViewRender()
-	case addTaskView:
-		return m.addTaskViewRender()
-	case reportView:
-		return m.reportViewRender()
-	case helpView:
-		return m.helpViewRender()
-	default:
-		return "Unknown view"
+// pomodoroStatus is the running pomodoro's phase and time left
+func (m model) pomodoroStatus(now time.Time) string {
+	left := m.pomoEnd.Sub(now).Round(time.Second)
+	countdown := fmt.Sprintf("%02d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+	if m.pomoPhase == "break" {
+		return iconTomato.String() + "Break: " + countdown + " left"
 	}
+	return iconTomato.String() + "Focus on " + m.pomoTask + ": " + countdown + " left"
 }
 
-func (m model) mainViewRender() string {
-	title := titleStyle.Render("⏱️  Time Tracker")
-	
-	// Current status
-	status := m.tracker.getCurrentStatus()
-	
-	// Recent activities (last 5)
-	recentActivities := m.tracker.getRecentActivities(5)
-	var recent strings.Builder
-	recent.WriteString(subtitleStyle.Render("Recent Activities:") + "\n\n")
+func (m model) updateReflectView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	
-	if len(recentActivities) == 0 {
-		recent.WriteString(infoStyle.Render("No activities yet. Press 's' to start your day or 'a' to complete a task."))
-	} else {
-		for _, activity := range recentActivities {
-			timeStr := activity.Start.Format("15:04") + "-" + activity.End.Format("15:04")
-			durationStr := formatDuration(activity.Duration)
-			
-			var style lipgloss.Style
-			switch activity.Type {
-			case Work:
-				style = workStyle
-			case Break:
-				style = breakStyle
-			case Ignored:
-				style = ignoredStyle
-			}
-			
-			// Use a simple, consistent format
-			line := fmt.Sprintf("  %s  %s  %s", timeStr, durationStr, activity.Name)
-			recent.WriteString(style.Render(line) + "\n")
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.currentView = mainView
+		m.reflectInput.Blur()
+	case key.Matches(msg, keys.Enter):
+		err := m.tracker.setReflection(m.tracker.todayStart(time.Now()), m.reflectInput.Value())
+		if err != nil {
+			m.message = fmt.Sprintf("Error saving reflection: %v", err)
+			m.messageType = "error"
+		} else {
+			m.message = "Reflection saved"
+			m.messageType = "success"
 		}
+		m.currentView = mainView
+		m.reflectInput.Blur()
+	default:
+		m.reflectInput, cmd = m.reflectInput.Update(msg)
+		return m, cmd
 	}
+	return m, nil
+}
+
+func (m model) updateNoteView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	
-	// Quick stats
-	stats := m.tracker.getTodaysStats()
-	quickStats := fmt.Sprintf("\n%s\n%s\n%s\n%s",
-		subtitleStyle.Render("Today's Summary:"),
-		workStyle.Render(fmt.Sprintf("  Work:  %s", formatDuration(stats.WorkTime))),
-		breakStyle.Render(fmt.Sprintf("  Break: %s", formatDuration(stats.BreakTime))),
-		subtitleStyle.Render(fmt.Sprintf("  Total: %s", formatDuration(stats.TotalTime))))
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.currentView = mainView
+		m.noteInput.Blur()
+	case key.Matches(msg, keys.Enter):
+		entry, err := m.tracker.addNote(m.noteInput.Value())
+		if err != nil {
+			m.message = fmt.Sprintf("Error adding note: %v", err)
+			m.messageType = "error"
+		} else {
+			m.message = fmt.Sprintf("Note added to '%s'", entry.Name)
+			m.messageType = "success"
+		}
+		m.currentView = mainView
+		m.noteInput.Blur()
+	default:
+		m.noteInput, cmd = m.noteInput.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m model) updateAddTaskView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	
-	// Project breakdown for main view
-	projects := m.tracker.getTodaysProjects()
-	// Debug: Always show the projects section to see what's in it
-	quickStats += "\n\n" + subtitleStyle.Render("Projects:")
-	if len(projects) == 0 {
-		quickStats += "\n" + infoStyle.Render("  No projects found")
-	} else {
-		for project, duration := range projects {
-			if project == "" {
-				project = "General"
-			}
-			quickStats += "\n" + workStyle.Render(fmt.Sprintf("  %s: %s", project, formatDuration(duration)))
+	// Quitting with something typed takes a second ctrl+c, and keeps the
+	// draft for the next launch
+	draft := m.addTaskDraft()
+	if key.Matches(msg, keys.Interrupt) {
+		if draft.empty() {
+			return m, tea.Quit
 		}
+		if m.quitPending {
+			saveDraft(draft)
+			return m, tea.Quit
+		}
+		m.quitPending = true
+		m.message = "Press ctrl+c again to quit; what you typed will be back next time"
+		m.messageType = "warning"
+		return m, nil
+	}
+	if m.quitPending {
+		m.quitPending = false
+		m.message = ""
 	}
 	
-	// Message
-	var message string
-	if m.message != "" {
-		switch m.messageType {
-		case "error":
-			message = "\n" + errorStyle.Render("• "+m.message)
-		case "success":
-			message = "\n" + successStyle.Render("• "+m.message)
+	// The multi-line comment editor owns Enter, so it has its own keys
+	if m.multiline {
+		switch {
+		case key.Matches(msg, keys.Back):
+			m.cancelAddTask()
+		case key.Matches(msg, keys.Save):
+			m.taskComment = m.commentArea.Value()
+			m.completeTask()
 		default:
-			message = "\n" + infoStyle.Render("• "+m.message)
+			m.commentArea, cmd = m.commentArea.Update(msg)
+			return m, cmd
 		}
+		return m, nil
 	}
 	
-	// Help
-	helpView := "\n" + helpStyle.Render("Press ? for help, q to quit")
-	
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		"",
-		status,
-		"",
-		recent.String(),
-		quickStats,
-		message,
-		helpView,
-	)
-	
-	return docStyle.Render(content)
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.cancelAddTask()
+		return m, nil
+	case m.inputMode == 1 && key.Matches(msg, keys.CycleType):
+		m.taskType = (m.taskType + 1) % (Ignored + 1)
+		return m, nil
+	case m.inputMode == 1 && key.Matches(msg, keys.Expand):
+		// Switch the comment to a multi-line editor, keeping what was typed
+		m.multiline = true
+		m.commentArea.SetValue(m.taskInput.Value())
+		m.taskInput.Blur()
+		return m, m.commentArea.Focus()
+	case m.inputMode == 0 && !m.editingTime && len(m.gitCommits) > 0 && key.Matches(msg, keys.Suggest):
+		m.gitCursor = (m.gitCursor + 1) % len(m.gitCommits)
+		m.taskInput.SetValue(m.gitCommits[m.gitCursor].Subject)
+		m.taskInput.CursorEnd()
+		return m, nil
+	case m.inputMode == 0 && key.Matches(msg, keys.SwitchField):
+		m.editingTime = !m.editingTime
+		if m.editingTime {
+			m.taskInput.Blur()
+			return m, m.timeInput.Focus()
+		}
+		m.timeInput.Blur()
+		return m, m.taskInput.Focus()
+	case key.Matches(msg, keys.Enter):
+		if m.inputMode == 0 {
+			// Save task name and move to comment
+			m.taskName = m.tracker.config.expandShortcuts(m.taskInput.Value())
+			if name, _ := splitMeta(m.taskName); !m.tracker.config.parser().hasTaskName(name) {
+				m.message = "Task name cannot be empty"
+				m.messageType = "error"
+				return m, nil
+			}
+			m.taskTime = time.Time{}
+			if value := strings.TrimSpace(m.timeInput.Value()); value != "" {
+				t, err := parseEntryTime(m.tracker.config.display(), value, time.Now())
+				if err == nil {
+					err = m.tracker.checkBackdate(t, false)
+				}
+				if err != nil {
+					m.message = fmt.Sprintf("Error: %v", err)
+					m.messageType = "error"
+					return m, nil
+				}
+				m.taskTime = t
+			}
+			m.message = ""
+			m.editingTime = false
+			m.timeInput.Blur()
+			m.inputMode = 1
+			m.taskType = m.tracker.config.parser().parseName(m.taskName).Type
+			m.taskInput.SetValue(m.taskComment)
+			m.taskInput.Placeholder = "Optional comment (press Enter to skip)"
+			m.taskInput.Focus()
+		} else {
+			// Save comment and add task
+			m.taskComment = m.taskInput.Value()
+			m.completeTask()
+		}
+		return m, nil
+	default:
+		// Let the focused text input handle other keys
+		if m.editingTime {
+			m.timeInput, cmd = m.timeInput.Update(msg)
+			return m, cmd
+		}
+		m.taskInput, cmd = m.taskInput.Update(msg)
+		return m, cmd
+	}
 }
 
-func (m model) addTaskViewRender() string {
-	title := titleStyle.Render("✅ Task Completed")
-	
-	var prompt string
-	if m.inputMode == 0 {
-		prompt = subtitleStyle.Render("What task did you just finish?")
-		prompt += "\n" + infoStyle.Render("Examples: 'Meeting: Standup', 'Lunch **', 'Commuting ***'")
-		
-		// Show duration since last activity
-		if len(m.tracker.entries) > 0 {
-			lastEntry := m.tracker.entries[len(m.tracker.entries)-1]
-			duration := time.Since(lastEntry.Timestamp)
-			prompt += "\n" + workStyle.Render(fmt.Sprintf("Duration: %s (since %s)", 
-				formatDuration(duration), lastEntry.Timestamp.Format("15:04")))
+// finishTime is when the task in the add form finished
+func (m model) finishTime() time.Time {
+	if m.taskTime.IsZero() {
+		return time.Now()
+	}
+	return m.taskTime
+}
+
+// completeTask logs the task collected by the add form and resets the form
+func (m *model) completeTask() {
+	show := m.tracker.config.display()
+	name, meta := splitMeta(m.taskName)
+	name, tags := splitTags(name)
+	if !m.tracker.config.parser().hasTaskName(name) {
+		m.message = "Task name cannot be empty"
+		m.messageType = "error"
+		return
+	}
+	if m.gitCursor >= 0 && m.gitCursor < len(m.gitCommits) && m.gitCommits[m.gitCursor].Subject == m.taskName {
+		if meta == nil {
+			meta = map[string]string{}
 		}
+		meta["commit"] = m.gitCommits[m.gitCursor].Hash
+	}
+	entry := Entry{
+		Timestamp: m.finishTime(),
+		Name:      name,
+		Comment:   m.taskComment,
+		Type:      m.tracker.config.parser().explicitType(name, m.taskType),
+		Meta:      meta,
+		Tags:      tags,
+	}
+	
+	gap, idle := m.tracker.idleGap(entry)
+	bells, err := m.tracker.logTask(entry)
+	m.bells = bells
+	if err != nil {
+		m.message = fmt.Sprintf("Error adding task: %v", err)
+		m.messageType = "error"
+	} else if idle {
+		m.ask(idleQuestion(show, gap), fmt.Sprintf("Task completed: %s (idle time split off)", name), func() error {
+			return m.tracker.splitIdle(entry)
+		})
+		m.currentView = mainView
+		m.taskInput.Blur()
 	} else {
-		prompt = subtitleStyle.Render("Comment (optional):")
-		prompt += "\n" + infoStyle.Render("Task: ") + workStyle.Render(m.taskName)
-		
-		// Show the duration this task will have
-		if len(m.tracker.entries) > 0 {
-			lastEntry := m.tracker.entries[len(m.tracker.entries)-1]
-			duration := time.Since(lastEntry.Timestamp)
-			prompt += "\n" + workStyle.Render(fmt.Sprintf("This task took: %s", formatDuration(duration)))
+		// Calculate duration from last entry
+		var durationMsg string
+		if lastEntry, ok := m.tracker.lastEntryBefore(entry.Timestamp); ok && lastEntry.Name != "Stop" {
+			duration := entry.Timestamp.Sub(lastEntry.Timestamp)
+			durationMsg = fmt.Sprintf(" (%s)", show.span(duration))
 		}
+		m.message = fmt.Sprintf("Task completed: %s%s", name, durationMsg)
+		m.messageType = "success"
+		m.currentView = mainView
+		m.taskInput.Blur()
 	}
 	
-	input := m.taskInput.View()
-	
+	m.resetAddTaskForm()
+}
+
+// addTaskDraft is what was typed into the add form, kept in draft.json when
+// tt quits halfway through so the next launch can pick it up again
+type addTaskDraft struct {
+	Name     string `json:"name"`
+	Comment  string `json:"comment,omitempty"`
+	Finished string `json:"finished,omitempty"` // As typed into the finish time field
+}
+
+func (d addTaskDraft) empty() bool {
+	return strings.TrimSpace(d.Name+d.Comment+d.Finished) == ""
+}
+
+// draftFile is where the add form's draft waits for the next launch
+func draftFile() string {
+	return filepath.Join(filepath.Dir(configFile()), "draft.json")
+}
+
+func saveDraft(draft addTaskDraft) error {
+	data, err := json.MarshalIndent(draft, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(draftFile(), data, 0644)
+}
+
+// addTaskDraft collects what the add form holds so far
+func (m model) addTaskDraft() addTaskDraft {
+	if m.inputMode == 0 {
+		return addTaskDraft{Name: m.taskInput.Value(), Finished: m.timeInput.Value()}
+	}
+	draft := addTaskDraft{Name: m.taskName, Comment: m.taskInput.Value(), Finished: m.timeInput.Value()}
+	if m.multiline {
+		draft.Comment = m.commentArea.Value()
+	}
+	return draft
+}
+
+// restoreDraft reopens the add form with the draft the last session quit
+// with, if any. The draft is only restored once.
+func (m *model) restoreDraft() {
+	data, err := os.ReadFile(draftFile())
+	if err != nil {
+		return
+	}
+	os.Remove(draftFile())
+	var draft addTaskDraft
+	if err := json.Unmarshal(data, &draft); err != nil || draft.empty() {
+		return
+	}
+	m.currentView = addTaskView
+	m.resetAddTaskForm()
+	m.taskInput.SetValue(draft.Name)
+	m.taskInput.CursorEnd()
+	m.taskInput.Focus()
+	m.timeInput.SetValue(draft.Finished)
+	m.taskComment = draft.Comment
+	m.message = "Restored the task you were adding when tt quit"
+	m.messageType = "info"
+}
+
+// cancelAddTask leaves the add form without logging anything
+func (m *model) cancelAddTask() {
+	m.currentView = mainView
+	m.taskInput.Blur()
+	m.message = ""
+	m.resetAddTaskForm()
+}
+
+func (m *model) resetAddTaskForm() {
+	m.taskName = ""
+	m.taskComment = ""
+	m.inputMode = 0
+	m.multiline = false
+	m.taskType = Work
+	m.taskTime = time.Time{}
+	m.editingTime = false
+	m.gitCommits = nil
+	m.gitCursor = -1
+	m.timeInput.Reset()
+	m.timeInput.Blur()
+	m.commentArea.Reset()
+	m.commentArea.Blur()
+	m.taskInput.Placeholder = "Enter task name (e.g., 'Education: CKA Labs' or 'Lunch " + m.tracker.config.BreakMarker + "')"
+}
+
+func (m model) updateReportView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editing {
+		return m.updateEntryEdit(msg)
+	}
+	if m.splitting {
+		return m.updateEntrySplit(msg)
+	}
+	if m.searching {
+		return m.updateReportSearch(msg)
+	}
+	
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, keys.Search):
+		m.searching = true
+		m.searchInput.SetValue(m.reportSearch)
+		m.searchInput.CursorEnd()
+		m.message = ""
+		return m, m.searchInput.Focus()
+	case key.Matches(msg, keys.Edit):
+		if len(m.reportRows) == 0 {
+			break
+		}
+		index, err := m.tracker.entryIndex(m.reportRows[m.table.Cursor()])
+		if err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			break
+		}
+		m.editing = true
+		m.editIndex = index
+		m.editField = 0
+		m.editInput.SetValue(nameWithTags(m.tracker.entries[index]))
+		m.editInput.CursorEnd()
+		m.message = ""
+		return m, m.editInput.Focus()
+	case key.Matches(msg, keys.Split):
+		if len(m.reportRows) == 0 {
+			break
+		}
+		activity := m.reportRows[m.table.Cursor()]
+		index, err := m.tracker.entryIndex(activity)
+		if err == nil && activity.Duration < 2*time.Minute {
+			err = errors.New("too short to split")
+		}
+		if err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			break
+		}
+		// Default to the midpoint
+		m.splitting = true
+		m.editIndex = index
+		m.splitStart = activity.Start
+		m.editInput.SetValue(strconv.Itoa(int(activity.Duration.Minutes()) / 2))
+		m.editInput.CursorEnd()
+		m.message = ""
+		return m, m.editInput.Focus()
+	case key.Matches(msg, keys.Back):
+		m.currentView = mainView
+		m.reportSearch = ""
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.FilterTypes):
+		m.reportTypes = nextTypeFilter(m.reportTypes)
+		m.updateReportData()
+	case key.Matches(msg, keys.Span):
+		m.reportSpan = nextReportSpan[m.reportSpan]
+		m.updateReportData()
+	case key.Matches(msg, keys.Elapsed):
+		m.tracker.config.IncludeIgnored = !m.tracker.config.IncludeIgnored
+		m.updateReportData()
+	case key.Matches(msg, keys.Heatmap):
+		m.reportHeatmap = !m.reportHeatmap
+		m.updateReportData()
+	case key.Matches(msg, keys.DurationMode):
+		m.durationMode = nextDurationMode[m.durationMode]
+		m.updateReportData()
+	case key.Matches(msg, keys.Sort):
+		m.reportSort = m.tracker.config.nextSortColumn(m.reportSort)
+		m.updateReportData()
+	case key.Matches(msg, keys.Reverse):
+		m.reportDesc = !m.reportDesc
+		m.updateReportData()
+	case key.Matches(msg, keys.Left):
+		start, _, _ := m.reportRange()
+		m.reportDay = m.shiftReportSpan(start, -1)
+		m.updateReportData()
+	case key.Matches(msg, keys.Right):
+		// No browsing past today
+		start, _, _ := m.reportRange()
+		if next := m.shiftReportSpan(start, 1); !next.After(time.Now()) {
+			m.reportDay = next
+			m.updateReportData()
+		}
+	default:
+		// Up/down move the table's selection
+		m.table, cmd = m.table.Update(msg)
+	}
+	return m, cmd
+}
+
+// updateReportSearch filters the report table as the search is typed. Enter
+// keeps the filter, Esc clears it.
+func (m model) updateReportSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.reportSearch = ""
+		m.searching = false
+		m.searchInput.Blur()
+	case key.Matches(msg, keys.Enter):
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	default:
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		m.reportSearch = strings.TrimSpace(m.searchInput.Value())
+	}
+	m.updateReportData()
+	m.table.SetCursor(0)
+	return m, cmd
+}
+
+// updateEntryEdit handles keys while an entry's name, then its comment, is
+// being edited from the report table
+func (m model) updateEntryEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.editing = false
+		m.editInput.Blur()
+	case key.Matches(msg, keys.Enter):
+		if m.editField == 0 {
+			m.editName = m.editInput.Value()
+			m.editField = 1
+			m.editInput.SetValue(m.tracker.entries[m.editIndex].Comment)
+			m.editInput.CursorEnd()
+			break
+		}
+		if err := m.tracker.editEntry(m.editIndex, m.editName, m.editInput.Value()); err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+		} else {
+			m.message = "Entry updated"
+			m.messageType = "success"
+		}
+		m.editing = false
+		m.editInput.Blur()
+		m.updateReportData()
+	default:
+		m.editInput, cmd = m.editInput.Update(msg)
+	}
+	return m, cmd
+}
+
+// updateEntrySplit handles keys while the minute to split an entry at is
+// being typed
+func (m model) updateEntrySplit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, keys.Back):
+		m.splitting = false
+		m.editInput.Blur()
+	case key.Matches(msg, keys.Enter):
+		minutes, err := strconv.Atoi(strings.TrimSpace(m.editInput.Value()))
+		if err != nil {
+			m.message = "Error: enter the minutes into the task to split at"
+			m.messageType = "error"
+			break
+		}
+		if err := m.tracker.splitEntry(m.editIndex, m.splitStart, time.Duration(minutes)*time.Minute); err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			m.messageType = "error"
+			break
+		}
+		m.message = fmt.Sprintf("Entry split at %s, press e to rename either part", m.tracker.config.display().clock(m.splitStart.Add(time.Duration(minutes)*time.Minute)))
+		m.messageType = "success"
+		m.splitting = false
+		m.editInput.Blur()
+		m.updateReportData()
+	default:
+		m.editInput, cmd = m.editInput.Update(msg)
+	}
+	return m, cmd
+}
+
+// nextReportSpan cycles the report view through a day, a week and a month
+var nextReportSpan = map[string]string{"": "week", "day": "week", "week": "month", "month": "day"}
+
+// nextDurationMode cycles the report's duration column through the time, the
+// share of the rows shown and a bar of that share
+var nextDurationMode = map[string]string{"": "percent", "percent": "bar", "bar": ""}
+
+// durationTitles heads the duration column in each duration mode
+var durationTitles = map[string]string{"": "Duration", "percent": "% of shown", "bar": "Share shown"}
+
+// setDurationTitle heads the duration column for the current duration mode
+func (m *model) setDurationTitle() {
+	columns := m.table.Columns()
+	i := 0
+	for _, column := range m.tracker.config.reportColumns() {
+		if _, ok := reportColumnTitles[column.Name]; !ok {
+			continue
+		}
+		if column.Name == "duration" && i < len(columns) {
+			columns[i].Title = durationTitles[m.durationMode]
+		}
+		i++
+	}
+	m.table.SetColumns(columns)
+}
+
+// durationBarWidth is the length of the bars in the duration column
+const durationBarWidth = 10
+
+// durationCell is the duration column of activity in mode, relative to total
+func durationCell(show displayFormats, activity Activity, total time.Duration, mode string) string {
+	share := 0.0
+	if total > 0 {
+		share = float64(activity.Duration) / float64(total)
+	}
+	switch mode {
+	case "percent":
+		return fmt.Sprintf("%.0f%%", share*100)
+	case "bar":
+		return progressBar(durationBarWidth, share)
+	}
+	return show.duration(activity.Duration)
+}
+
+// shiftReportSpan moves t by n of the report view's spans
+func (m model) shiftReportSpan(t time.Time, n int) time.Time {
+	switch m.reportSpan {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// reportRange is the [start, end) period the report view shows, around
+// reportDay, and its heading
+func (m model) reportRange() (start, end time.Time, heading string) {
+	now := time.Now()
+	switch m.reportSpan {
+	case "week":
+		start = startOfWeek(m.reportDay, m.tracker.config.weekStartDay())
+		end = start.AddDate(0, 0, 7)
+		heading = "Report: week of " + start.Format("Mon 2006-01-02")
+		if !now.Before(start) && now.Before(end) {
+			heading = "This Week's Report"
+		}
+	case "month":
+		start = time.Date(m.reportDay.Year(), m.reportDay.Month(), 1, 0, 0, 0, 0, m.reportDay.Location())
+		end = start.AddDate(0, 1, 0)
+		heading = "Report: " + start.Format("January 2006")
+		if !now.Before(start) && now.Before(end) {
+			heading = "This Month's Report"
+		}
+	default:
+		start = m.tracker.dayStartOn(m.reportDay)
+		end = start.AddDate(0, 0, 1)
+		heading = "Today's Report"
+		if !start.Equal(m.tracker.todayStart(now)) {
+			heading = "Report: " + start.Format("Mon 2006-01-02")
+		}
+	}
+	return start, end, heading
+}
+
+// multiDay reports whether the report view spans more than a day
+func (m model) multiDay() bool {
+	return m.reportSpan == "week" || m.reportSpan == "month"
+}
+
+// nextTypeFilter returns the filter after current in allTypeFilters
+func nextTypeFilter(current map[ActivityType]bool) map[ActivityType]bool {
+	for i, filter := range allTypeFilters {
+		if len(filter) == len(current) && typeFilterLabel(filter) == typeFilterLabel(current) {
+			return allTypeFilters[(i+1)%len(allTypeFilters)]
+		}
+	}
+	return allTypeFilters[0]
+}
+
+// typeFilterLabel lists the types in a filter, e.g. "WORK, BREAK"
+func typeFilterLabel(filter map[ActivityType]bool) string {
+	var names []string
+	for _, t := range []ActivityType{Work, Break, Ignored} {
+		if filter[t] {
+			names = append(names, t.String())
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (m model) updateHelpView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Back), key.Matches(msg, keys.Help):
+		m.currentView = mainView
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *model) updateReportData() {
+	show := m.tracker.config.display()
+	if m.reportDay.IsZero() {
+		m.reportDay = m.tracker.todayStart(time.Now())
+	}
+	start, end, _ := m.reportRange()
+	activities := reportOptions{RoundTo: m.tracker.config.roundTo(), Merge: m.tracker.config.MergeConsecutive}.apply(m.tracker.activitiesIn(start, end))
+	
+	// The type filter and sort only affect the table; the summary uses every activity
+	rows := []table.Row{}
+	m.reportRows = nil
+	for _, activity := range activities {
+		if !m.reportTypes[activity.Type] || !activity.matches(m.reportSearch) {
+			continue
+		}
+		m.reportRows = append(m.reportRows, activity)
+	}
+	sortActivities(show, m.reportRows, m.reportSort, m.reportDesc)
+	
+	// Shares are of the rows shown, so they add up to 100% whatever is filtered out
+	var total time.Duration
+	for _, activity := range m.reportRows {
+		total += activity.Duration
+	}
+	m.setDurationTitle()
+	for _, activity := range m.reportRows {
+		row := table.Row{}
+		for _, column := range m.tracker.config.reportColumns() {
+			if cell, ok := columnCell(show, column.Name, activity); ok {
+				// Over several days the date says more than the clock
+				if column.Name == "time" && m.multiDay() {
+					cell = activity.End.Format("Mon 01-02")
+				}
+				if over := m.tracker.config.breakOverrun(activity); column.Name == "activity" && over > 0 {
+					cell += "  " + overrunNote(show, over)
+				}
+				if column.Name == "duration" {
+					cell = durationCell(show, activity, total, m.durationMode)
+				}
+				row = append(row, cell)
+			}
+		}
+		rows = append(rows, row)
+	}
+	
+	m.table.SetRows(rows)
+	
+	// Generate summary for viewport
+	if m.reportHeatmap {
+		var heatmap strings.Builder
+		heatmap.WriteString(subtitleStyle.Render("Work by Hour of Day:") + "\n\n")
+		for _, line := range heatmapLines(show, hourlyTotals(activities), 30) {
+			heatmap.WriteString(workStyle.Render(line) + "\n")
+		}
+		m.viewport.SetContent(heatmap.String())
+		return
+	}
+	summary := m.tracker.generateSummary(activities)
+	m.viewport.SetContent(summary)
+}
+
+func (m model) View() string {
+	if !m.ready {
+		return "\n  Initializing..."
+	}
+
+	switch m.currentView {
+	case mainView:
+		return m.mainViewRender()
+	case addTaskView:
+		return m.addTaskViewRender()
+	case reportView:
+		return m.reportViewRender()
+	case helpView:
+		return m.helpViewRender()
+	case reflectView:
+		return m.reflectViewRender()
+	case profilesView:
+		return m.profilesViewRender()
+	case pomodoroView:
+		return m.pomodoroViewRender()
+	case noteView:
+		return m.noteViewRender()
+	default:
+		return "Unknown view"
+	}
+}
+
+func (m model) mainViewRender() string {
+	show := m.tracker.config.display()
+	title := titleStyle.Render(iconTimer.String() + "Time Tracker")
+	if profile := m.tracker.activeProfile(); profile != defaultProfile {
+		title = titleStyle.Render(iconTimer.String() + "Time Tracker · " + profile)
+	}
+	
+	// Current status
+	status := m.tracker.getCurrentStatus()
+	if m.configErr != nil {
+		status = errorStyle.Render(fmt.Sprintf("Config error, some settings use defaults: %v", m.configErr)) + "\n\n" + status
+	}
+	if m.tracker.corrupt != nil {
+		status = errorStyle.Render(fmt.Sprintf("Data file error: %v\n%s", m.tracker.corrupt, m.tracker.recoveryNote())) + "\n\n" + status
+	}
+	
+	// Build today's activities once per render
+	activities := m.tracker.getTodaysActivities()
+	
+	if m.pomoPhase != "" {
+		status += "\n" + currentActivityStyle.Render(m.pomodoroStatus(time.Now()))
+	}
+	
+	if m.tracker.config.showBillable() {
+		status += "\n" + successStyle.Render(fmt.Sprintf("Billable today: %s",
+			show.duration(m.tracker.config.billableTime(activities))))
+	}
+	
+	// Nudge to start tracking when today has nothing yet but earlier days do
+	today := m.tracker.todayStart(time.Now())
+	notStarted := len(m.tracker.entries) > 0 && len(m.tracker.entriesIn(today, today.AddDate(0, 0, 1))) == 0
+	if notStarted {
+		status += "\n\n" + reminderStyle.Render("Nothing logged today yet. Press 's' to start your day or 'a' to log your first task.")
+	}
+	if since, elapsed, long := m.tracker.longTask(time.Now()); long {
+		status += "\n\n" + reminderStyle.Render(fmt.Sprintf("%sThe current task has run for %s, since %s. Log it, or take a break?",
+			iconWarning, show.span(elapsed), show.clock(since)))
+	}
+	if note := m.tracker.longDayNote(today); note != "" && m.longDayDismissed != dayKey(today) {
+		status += "\n\n" + reminderStyle.Render(note+" (d to dismiss)")
+	}
+	
+	// Recent activities (last 5)
+	recent5 := recentActivities(activities, 5)
+	var recent strings.Builder
+	recent.WriteString(subtitleStyle.Render("Recent Activities:") + "\n\n")
+	
+	if notStarted {
+		recent.WriteString(infoStyle.Render("No activities today."))
+	} else if len(recent5) == 0 {
+		recent.WriteString(infoStyle.Render("No activities yet. Press 's' to start your day or 'a' to complete a task."))
+	} else {
+		for _, activity := range recent5 {
+			timeStr := show.clockSpan(activity.Start, activity.End)
+			durationStr := show.duration(activity.Duration)
+			
+			// Use a simple, consistent format
+			line := fmt.Sprintf("  %s  %s  %s", timeStr, durationStr, activity.Name)
+			recent.WriteString(typeStyle(activity.Type).Render(line) + "\n")
+		}
+	}
+	
+	// Quick stats
+	stats := computeStats(activities)
+	quickStats := fmt.Sprintf("\n%s\n%s\n%s\n%s",
+		subtitleStyle.Render("Today's Summary:"),
+		workStyle.Render(fmt.Sprintf("  Work:  %s", show.duration(stats.WorkTime))),
+		breakStyle.Render(fmt.Sprintf("  Break: %s", show.duration(stats.BreakTime))),
+		subtitleStyle.Render(fmt.Sprintf("  Total: %s", show.duration(stats.TotalTime))))
+	if split := workBreakSplit(stats, 20); split != "" {
+		quickStats += "\n" + split
+	}
+	if m.tracker.config.IncludeIgnored {
+		quickStats += "\n" + ignoredStyle.Render(fmt.Sprintf("  Elapsed: %s (with %s ignored)",
+			show.duration(stats.Elapsed()), show.duration(stats.IgnoredTime)))
+	}
+	
+	// Week-to-date progress toward the weekly goal
+	if goal := m.tracker.config.WeeklyGoalHours; goal > 0 {
+		now := time.Now()
+		weekStart := startOfWeek(now, m.tracker.config.weekStartDay())
+		week := computeStats(m.tracker.getActivitiesBetween(weekStart, now))
+		progress := week.WorkTime.Hours() / goal
+		
+		daysLeft := 0
+		for day := startOfDay(now).AddDate(0, 0, 1); day.Before(weekStart.AddDate(0, 0, 7)); day = day.AddDate(0, 0, 1) {
+			daysLeft++
+		}
+		
+		goalDuration := hoursDuration(goal)
+		quickStats += "\n" + weeklyGoalStyle(progress, daysLeft).Render(fmt.Sprintf("  Week:  %s / %s  %s",
+			show.duration(week.WorkTime), show.duration(goalDuration), progressBar(20, progress)))
+	}
+	
+	// Progress toward the daily target; breaks and ignored time don't count
+	if target := m.tracker.config.DailyTargetHours; target > 0 {
+		progress := stats.WorkTime.Hours() / target
+		style := workStyle
+		if progress >= 1 {
+			style = successStyle
+		}
+		quickStats += "\n" + style.Render(fmt.Sprintf("  Day:   %s / %s  %s",
+			show.duration(stats.WorkTime), show.duration(hoursDuration(target)), progressBar(20, progress)))
+		if streak := m.tracker.streak(time.Now()); streak > 0 {
+			quickStats += "\n" + successStyle.Render(fmt.Sprintf("  %s%d day streak", iconStreak, streak))
+		}
+	}
+	
+	// Where today is heading at the current rate
+	if m.tracker.config.DailyTargetHours > 0 && !notStarted && len(activities) > 0 {
+		quickStats += "\n" + infoStyle.Render("  "+m.tracker.pace(time.Now()))
+	}
+	
+	// Project breakdown for main view
+	projects := computeProjects(activities)
+	// Debug: Always show the projects section to see what's in it
+	quickStats += "\n\n" + subtitleStyle.Render("Projects:")
+	if len(projects) == 0 {
+		quickStats += "\n" + infoStyle.Render("  No projects found")
+	} else {
+		lines := formatProjectLines(show, sortedProjects(projects), m.tracker.config.ProjectDepth > 1)
+		for i, line := range lines {
+			style := workStyle
+			if i == len(lines)-1 {
+				style = subtitleStyle
+			}
+			quickStats += "\n" + style.Render(line)
+		}
+	}
+	
+	// Message
 	var message string
 	if m.message != "" {
 		switch m.messageType {
 		case "error":
-			message = errorStyle.Render("• " + m.message)
-		default:
+			message = "\n" + errorStyle.Render("• "+m.message)
+		case "success":
+			message = "\n" + successStyle.Render("• "+m.message)
+		case "warning":
+			message = "\n" + warningStyle.Render("• "+m.message)
+		default:
+			message = "\n" + infoStyle.Render("• "+m.message)
+		}
+	}
+	
+	// Quick tasks with their keys
+	if quick := m.tracker.config.QuickTasks; len(quick) > 0 {
+		var labels []string
+		for i, name := range quick[:min(len(quick), 9)] {
+			labels = append(labels, fmt.Sprintf("%d %s", i+1, name))
+		}
+		message += "\n\n" + subtitleStyle.Render("Quick tasks: ") + workStyle.Render(strings.Join(labels, "  "))
+	}
+	
+	// Help
+	helpView := "\n" + helpStyle.Render("Press ? for help, q to quit")
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		status,
+		"",
+		recent.String(),
+		quickStats,
+		message,
+		helpView,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) addTaskViewRender() string {
+	show := m.tracker.config.display()
+	title := titleStyle.Render(iconDone.String() + "Task Completed")
+	
+	var prompt string
+	if m.inputMode == 0 {
+		prompt = subtitleStyle.Render("What task did you just finish?")
+		prompt += "\n" + infoStyle.Render("Examples: 'Meeting: Standup', 'Lunch "+m.tracker.config.BreakMarker+"', 'Commuting "+m.tracker.config.IgnoredMarker+"'")
+		
+		// Show duration since last activity
+		if lastEntry, ok := m.tracker.lastEntry(); ok {
+			duration := time.Since(lastEntry.Timestamp)
+			prompt += "\n" + workStyle.Render(fmt.Sprintf("Duration: %s (since %s)", 
+				show.span(duration), show.clock(lastEntry.Timestamp)))
+		}
+	} else {
+		prompt = subtitleStyle.Render("Comment (optional):")
+		prompt += "\n" + infoStyle.Render("Task: ") + workStyle.Render(m.taskName)
+		
+		typeStyle := workStyle
+		switch m.taskType {
+		case Break:
+			typeStyle = breakStyle
+		case Ignored:
+			typeStyle = ignoredStyle
+		}
+		prompt += "\n" + infoStyle.Render("Type: ") + typeStyle.Render(m.taskType.String()) +
+			infoStyle.Render(" (ctrl+t to change)")
+		
+		// Show the duration this task will have
+		if lastEntry, ok := m.tracker.lastEntryBefore(m.finishTime()); ok {
+			duration := m.finishTime().Sub(lastEntry.Timestamp)
+			took := fmt.Sprintf("This task took: %s", show.span(duration))
+			if !m.taskTime.IsZero() {
+				took += " (finished " + show.clock(m.taskTime) + ")"
+			}
+			prompt += "\n" + workStyle.Render(took)
+		}
+	}
+	
+	input := m.taskInput.View()
+	if m.multiline {
+		input = m.commentArea.View()
+	} else if m.inputMode == 0 {
+		if value := m.taskInput.Value(); strings.Contains(value, "@") {
+			if expanded := m.tracker.config.expandShortcuts(value); expanded != value {
+				input += "\n" + infoStyle.Render("→ ") + workStyle.Render(expanded)
+			}
+		}
+		input += "\n" + infoStyle.Render("Finished at: ") + m.timeInput.View()
+		if len(m.gitCommits) > 0 {
+			input += "\n\n" + subtitleStyle.Render("Commits since the last entry (ctrl+g to pick):")
+			for i, commit := range m.gitCommits {
+				cursor, style := "  ", infoStyle
+				if i == m.gitCursor {
+					cursor, style = "▸ ", currentActivityStyle
+				}
+				input += "\n" + style.Render(fmt.Sprintf("%s%s  %s", cursor, show.clock(commit.Time), commit.Subject))
+			}
+		}
+	}
+	
+	var message string
+	if m.message != "" {
+		switch m.messageType {
+		case "error":
+			message = errorStyle.Render("• " + m.message)
+		default:
 			message = infoStyle.Render("• " + m.message)
 		}
 	}
 	
-	help := helpStyle.Render("Enter to continue • Esc to cancel")
+	help := helpStyle.Render("Enter to continue • Tab to set the finish time • Esc to cancel")
+	if m.inputMode == 0 && len(m.gitCommits) > 0 {
+		help = helpStyle.Render("Enter to continue • Ctrl+G for a commit • Tab to set the finish time • Esc to cancel")
+	}
+	if m.multiline {
+		help = helpStyle.Render("Enter for new line • Ctrl+S to save • Esc to cancel")
+	} else if m.inputMode == 1 {
+		help = helpStyle.Render("Enter to continue • Ctrl+E for multi-line comment • Esc to cancel")
+	}
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		prompt,
+		"",
+		input,
+		"",
+		message,
+		"",
+		help,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) reflectViewRender() string {
+	show := m.tracker.config.display()
+	title := titleStyle.Render(iconNote.String() + "Daily Reflection")
+	
+	stats := m.tracker.getTodaysStats()
+	summary := fmt.Sprintf("%s\n%s\n%s",
+		subtitleStyle.Render("Today:"),
+		workStyle.Render(fmt.Sprintf("  Work:  %s", show.duration(stats.WorkTime))),
+		breakStyle.Render(fmt.Sprintf("  Break: %s", show.duration(stats.BreakTime))))
+	
+	help := helpStyle.Render("Enter to save • Esc to cancel")
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		summary,
+		"",
+		subtitleStyle.Render("How did today go?"),
+		m.reflectInput.View(),
+		"",
+		help,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) noteViewRender() string {
+	title := titleStyle.Render(iconNote.String() + "Add a Note")
+	
+	task := infoStyle.Render("No task to add a note to")
+	if index, err := m.tracker.lastTaskIndex(); err == nil {
+		entry := m.tracker.entries[index]
+		task = workStyle.Render(fmt.Sprintf("%s  %s", m.tracker.config.display().clock(entry.Timestamp), entry.Name))
+		if entry.Comment != "" {
+			task += "\n" + infoStyle.Render(indentComment(entry.Comment, "  > "))
+		}
+	}
+	
+	help := helpStyle.Render("Enter to append to the comment • Esc to cancel")
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		subtitleStyle.Render("Last task:"),
+		task,
+		"",
+		m.noteInput.View(),
+		"",
+		help,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) pomodoroViewRender() string {
+	title := titleStyle.Render(iconTomato.String() + "Pomodoro")
+	cfg := m.tracker.config
+	
+	var body, help string
+	if m.pomoPhase == "" {
+		body = subtitleStyle.Render("What will you focus on?") + "\n" + m.pomoInput.View()
+		help = helpStyle.Render(fmt.Sprintf("Enter to start %d minutes of focus and a %d-minute break • Esc to go back",
+			int(cfg.pomodoroWork().Minutes()), int(cfg.pomodoroBreak().Minutes())))
+	} else {
+		length, style := cfg.pomodoroWork(), currentActivityStyle
+		if m.pomoPhase == "break" {
+			length, style = cfg.pomodoroBreak(), breakStyle
+		}
+		done := 1 - float64(m.pomoEnd.Sub(time.Now()))/float64(length)
+		body = style.Render(m.pomodoroStatus(time.Now())) + "\n\n" + style.Render(progressBar(40, done))
+		help = helpStyle.Render("c to cancel without logging • Esc to go back (the timer keeps running)")
+	}
+	
+	var message string
+	if m.message != "" {
+		message = "\n" + successStyle.Render("• "+m.message)
+		if m.messageType != "success" {
+			message = "\n" + infoStyle.Render("• "+m.message)
+		}
+	}
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		body,
+		"",
+		infoStyle.Render(fmt.Sprintf("Completed this session: %d", m.pomoDone)),
+		message,
+		"",
+		help,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) profilesViewRender() string {
+	title := titleStyle.Render(iconFolder.String() + "Profiles")
+	
+	nameWidth := 0
+	for _, p := range m.profiles {
+		nameWidth = max(nameWidth, len(p.Name))
+	}
+	
+	var list strings.Builder
+	for i, p := range m.profiles {
+		cursor, style := "  ", workStyle
+		if i == m.profileCursor {
+			cursor, style = "▸ ", currentActivityStyle
+		}
+		active := ""
+		if p.Active {
+			active = " (active)"
+		}
+		list.WriteString(style.Render(fmt.Sprintf("%s%-*s  today %s%s", cursor, nameWidth, p.Name, m.tracker.config.display().duration(p.Today), active)))
+		list.WriteString("\n" + infoStyle.Render("    "+p.DataFile) + "\n")
+	}
+	
+	help := helpStyle.Render("↑/↓ to choose • Enter to switch • Esc to go back")
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		list.String(),
+		help,
+	)
+	
+	return docStyle.Render(content)
+}
+
+func (m model) reportViewRender() string {
+	show := m.tracker.config.display()
+	start, end, heading := m.reportRange()
+	title := titleStyle.Render(iconReport.String() + heading)
+	reflection := m.tracker.days[dayKey(start)].Reflection
+	if reflection != "" && !m.multiDay() {
+		title += "\n" + infoStyle.Render(reflection)
+	}
+	
+	// Summary in viewport
+	summary := m.viewport.View()
+	if note := m.tracker.longDayNote(start); note != "" && !m.multiDay() {
+		summary += "\n" + warningStyle.Render(note)
+	}
+	
+	// How the day flowed, as wide as the summary box
+	activities := m.tracker.activitiesIn(start, end)
+	width := m.viewport.Width
+	if width < 20 {
+		width = 78
+	}
+	timeline := renderTimeline(show, activities, width)
+	
+	// Activities table with the total of the rows it lists, or a note when
+	// the period is empty
+	table := m.table.View()
+	if len(m.reportRows) > 0 {
+		var total time.Duration
+		for _, activity := range m.reportRows {
+			total += activity.Duration
+		}
+		table += "\n" + subtitleStyle.Render(fmt.Sprintf(" Total of %d shown: ", len(m.reportRows))) + workStyle.Render(show.duration(total))
+	}
+	if len(activities) == 0 {
+		table = infoStyle.Render(m.tracker.emptyDayMessage(start))
+		if m.multiDay() {
+			table = infoStyle.Render("No activities logged in this range.")
+		}
+	}
+	
+	help := helpStyle.Render("←/→ to move • w for day/week/month • t to filter types • / to search • o/O to sort • e to edit • s to split • m for hours • d for shares • Esc to go back • q to quit")
+	
+	// The entry being edited, or the outcome of the last edit
+	var edit string
+	if m.editing {
+		label := "Name: "
+		if m.editField == 1 {
+			label = "Comment: "
+		}
+		edit = subtitleStyle.Render(label) + m.editInput.View()
+		help = helpStyle.Render("Enter to continue • Esc to cancel")
+	} else if m.splitting {
+		label := fmt.Sprintf("Split at minutes after %s: ", show.clock(m.splitStart))
+		edit = subtitleStyle.Render(label) + m.editInput.View()
+		help = helpStyle.Render("Enter to split • Esc to cancel")
+	} else if m.searching {
+		edit = m.searchInput.View()
+		help = helpStyle.Render("Type to filter • Enter to keep the filter • Esc to clear it")
+	} else if m.message != "" {
+		style := successStyle
+		if m.messageType == "error" {
+			style = errorStyle
+		}
+		edit = style.Render("• " + m.message)
+	}
+	
+	sections := []string{title, "", summary, ""}
+	if !m.multiDay() {
+		sections = append(sections, timeline, "")
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left, append(sections,
+		subtitleStyle.Render("Activities:") + " " + infoStyle.Render("("+m.rowFilterLabel()+")"),
+		"",
+		table,
+		"",
+		edit,
+		help,
+	)...)
+	
+	return docStyle.Render(content)
+}
+
+// rowFilterLabel describes which rows the report table lists
+func (m model) rowFilterLabel() string {
+	label := typeFilterLabel(m.reportTypes)
+	if m.reportSearch != "" {
+		label += fmt.Sprintf(", matching %q", m.reportSearch)
+	}
+	if m.reportSort != "" {
+		order := "↑"
+		if m.reportDesc {
+			order = "↓"
+		}
+		label += fmt.Sprintf(", by %s %s", strings.ToLower(reportColumnTitles[m.reportSort]), order)
+	} else if m.reportDesc {
+		label += ", latest first"
+	}
+	return label
+}
+
+func (m model) helpViewRender() string {
+	title := titleStyle.Render(iconHelp.String() + "Help")
+	
+	helpContent := `
+` + subtitleStyle.Render("Navigation:") + `
+  ↑/k, ↓/j     Move up/down
+  ←/h, →/l     Move left/right
+  Enter        Select/confirm
+  Esc          Go back
+  q            Quit
+
+` + subtitleStyle.Render("Actions:") + `
+  s            Start day
+  a            Complete task (add finished task)
+  r            View today's report
+  x            Extend last task to now
+  f            Write today's reflection
+  c            Add a note to the last task's comment
+  ←/→          Previous/next day (in report)
+  t            Filter the report table by type (in report)
+  w            Switch the report between day, week and month
+  e            Edit the selected entry's name and comment (in report)
+  /            Search the report table by name or comment (in report)
+  s            Split the selected entry in two at a minute (in report)
+  o / O        Sort by the next column / reverse the order (in report)
+  m            Show work by hour of day instead of the summary (in report)
+  d            Show durations as time, share of the total or a bar (in report)
+  p            Switch profile
+  E            Open the data file in your editor, then reload it
+  1-9          Log a quick task
+  u            Remove the last entry (asks first)
+  d            Dismiss the long day note
+  i            Show elapsed time including ignored time
+  o            Pomodoro timer (c cancels a running one)
+  ?            Toggle this help
+
+` + subtitleStyle.Render("Adding a Task:") + `
+  Tab          Set when the task finished (HH:MM), if not now
+  Ctrl+G       Use the next commit made since the last entry as the name
+  Ctrl+T       Change the task type (work/break/ignored)
+  Ctrl+E       Expand the comment into a multi-line editor
+  Ctrl+S       Save a multi-line comment
+  Ctrl+C       Quit, keeping what you typed for next time (press twice)
+
+` + subtitleStyle.Render("Task Types:") + `
+  Regular task        "Meeting: Standup"
+` + fmt.Sprintf("  %-20s%q\n", "Break task ("+m.tracker.config.BreakMarker+")", "Lunch "+m.tracker.config.BreakMarker) +
+		fmt.Sprintf("  %-20s%q\n", "Ignored task ("+m.tracker.config.IgnoredMarker+")", "Commuting "+m.tracker.config.IgnoredMarker) + `
+` + subtitleStyle.Render("Project Format:") + `
+  Use "Project: Task" to categorize activities
+  Examples: "Education: CKA Labs", "Sprint-2: Bug fix"
+`
+	
+	back := helpStyle.Render("Press ? or Esc to go back")
+	
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		helpContent,
+		"",
+		back,
+	)
+	
+	return docStyle.Render(content)
+}
+
+// TimeTracker methods
+// loadConfig reads config.json over the defaults, creating it when missing.
+// A file that doesn't parse is reported in the returned error; whatever it
+// managed to set still applies, and everything else keeps its default.
+func (tt *TimeTracker) loadConfig() error {
+	configFile := configFile()
+	configDir := filepath.Dir(configFile)
+	
+	// Default config
+	tt.config = Config{
+		DataFile:             filepath.Join(configDir, "entries.json"),
+		Editor:               "",
+		WeekStart:            "monday",
+		MinExtendMinutes:     1,
+		AfternoonStartHour:   12,
+		EveningStartHour:     17,
+		CalendarProject:      "Meeting",
+		StartupAction:        "main",
+		ParallelTotals:       "once",
+		AutoCloseAtHour:      18,
+		OvernightGapHours:    4,
+		MaxWorkdayHours:      10,
+		BreakMarker:          "**",
+		IgnoredMarker:        "***",
+		ProjectDepth:         1,
+		Currency:             "$",
+		TimeFormat:           "24h",
+		DurationFormat:       "hm",
+		PomodoroWorkMinutes:  25,
+		PomodoroBreakMinutes: 5,
+		BackupRetentionDays:  30,
+		ArchiveAfterDays:     90,
+	}
+	
+	// Try to load existing config
+	var configErr error
+	if data, err := os.ReadFile(configFile); err == nil {
+		if err := json.Unmarshal(data, &tt.config); err != nil {
+			configErr = jsonError(configFile, data, err)
+		} else if err := tt.config.checkReportColumns(); err != nil {
+			configErr = fmt.Errorf("%s: %w", configFile, err)
+		}
+		if configErr == nil && tt.config.ConfigVersion < configVersion {
+			tt.migrateConfig(configFile, data)
+		}
+	} else {
+		// Create config directory and save default config
+		tt.config.ConfigVersion = configVersion
+		os.MkdirAll(configDir, 0755)
+		data, _ := json.MarshalIndent(tt.config, "", "  ")
+		os.WriteFile(configFile, data, 0644)
+	}
+	
+	
+	// The active profile (-p for this run, or else the one last switched to)
+	// swaps in its own data file, created on first use
+	tt.defaultDataFile = tt.config.DataFile
+	if profileOverride != "" {
+		tt.config.Profile = profileOverride
+	}
+	tt.config.DataFile = tt.profileDataFile(tt.config.Profile)
+	if tt.config.DataFile != tt.defaultDataFile {
+		os.MkdirAll(filepath.Dir(tt.config.DataFile), 0755)
+	}
+	
+	// TT_DATA_FILE wins over data_file and profiles, and -data-file over all
+	// of them: a data file named outright is used whatever profile is active
+	override := os.Getenv("TT_DATA_FILE")
+	if dataFileOverride != "" {
+		override = dataFileOverride
+	}
+	if override != "" {
+		tt.config.Profile = ""
+		tt.config.DataFile, tt.defaultDataFile = override, override
+	}
+	return configErr
+}
+
+// migrateConfig brings a config.json written by an older version up to
+// configVersion, rewriting only the settings that change. The "vi" every
+// config used to get as its editor becomes unset, once, so an editor set to
+// "vi" later on is kept.
+func (tt *TimeTracker) migrateConfig(path string, data []byte) {
+	settings := make(map[string]json.RawMessage)
+	if json.Unmarshal(data, &settings) != nil {
+		return
+	}
+	if tt.config.ConfigVersion < 1 && tt.config.Editor == "vi" {
+		tt.config.Editor = ""
+		settings["editor"] = json.RawMessage(`""`)
+	}
+	tt.config.ConfigVersion = configVersion
+	settings["config_version"], _ = json.Marshal(configVersion)
+	if migrated, err := json.MarshalIndent(settings, "", "  "); err == nil {
+		writeFileAtomic(path, migrated, 0644)
+	}
+}
+
+// jsonError names the file a JSON error came from and, for syntax and type
+// errors, the line and column where reading stopped
+func jsonError(path string, data []byte, err error) error {
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		offset = typeErr.Offset
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// Offset counts the byte where reading stopped
+	line, column := 1, 1
+	for _, b := range data[:max(offset-1, 0)] {
+		if b == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Errorf("%s, line %d column %d: %w", path, line, column, err)
+}
+
+// checkDataDir makes sure the data file's directory exists and can be
+// written, so a bad data_file fails before any change is lost
+func (tt *TimeTracker) checkDataDir() error {
+	dir := filepath.Dir(tt.config.DataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create the data directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to the data directory %s: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// profileOverride is the profile chosen with -p, used instead of the one in
+// config.json without changing it
+var profileOverride string
+
+// configDirOverride and dataFileOverride come from -config-dir and -data-file,
+// which win over TT_CONFIG_DIR and TT_DATA_FILE
+var configDirOverride, dataFileOverride string
+
+// configFile is the path of config.json: in -config-dir, TT_CONFIG_DIR or
+// ~/.config/timetracker, whichever is set first
+func configFile() string {
+	dir := configDirOverride
+	if dir == "" {
+		dir = os.Getenv("TT_CONFIG_DIR")
+	}
+	if dir == "" {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, ".config", "timetracker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+func (tt *TimeTracker) loadEntries() {
+	tt.entries = nil
+	tt.archived, tt.archivedMonths = nil, nil
+	tt.dayWork, tt.intervals, tt.dayActivities = nil, nil, nil
+	tt.corrupt, tt.recoveredFrom = nil, ""
+	if data, err := os.ReadFile(tt.config.DataFile); err == nil {
+		if err := json.Unmarshal(data, &tt.entries); err != nil {
+			// Never carry on from half a file: fall back to the latest
+			// backup and leave the damaged file alone until told otherwise
+			tt.corrupt = jsonError(tt.config.DataFile, data, err)
+			tt.entries, tt.recoveredFrom = tt.latestBackup()
+		}
+	}
+	
+	// Sort entries by timestamp
+	sortEntries(tt.entries)
+}
+
+// recoveryNote says what was loaded in place of a data file that couldn't be read
+func (tt *TimeTracker) recoveryNote() string {
+	if tt.recoveredFrom == "" {
+		return "No backup could be read either, so there are no entries."
+	}
+	return fmt.Sprintf("Using the backup %s instead (entries: %d).", tt.recoveredFrom, len(tt.entries))
+}
+
+// latestBackup reads the most recent backup of the data file that can be
+// read: the .bak left by bulk changes or one of the daily backups
+func (tt *TimeTracker) latestBackup() ([]Entry, string) {
+	candidates := []string{tt.config.DataFile + ".bak"}
+	prefix := strings.TrimSuffix(filepath.Base(tt.backupName(time.Now())), dayKey(time.Now())+".json")
+	if files, err := os.ReadDir(tt.backupDir()); err == nil {
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), prefix) && strings.HasSuffix(file.Name(), ".json") {
+				candidates = append(candidates, filepath.Join(tt.backupDir(), file.Name()))
+			}
+		}
+	}
+	
+	modified := make(map[string]time.Time)
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil {
+			modified[path] = info.ModTime()
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return modified[candidates[i]].After(modified[candidates[j]])
+	})
+	for _, path := range candidates {
+		if entries, err := readEntriesFile(path); err == nil {
+			return entries, path
+		}
+	}
+	return nil, ""
+}
+
+// editorCommand opens path in the configured editor, falling back to
+// $EDITOR and then vi. The editor may carry arguments, e.g. "code --wait".
+func (c Config) editorCommand(path string) *exec.Cmd {
+	editor := strings.TrimSpace(c.Editor)
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	return exec.Command(fields[0], append(fields[1:], path)...)
+}
+
+// editDataFile backs up the data file and returns the command that opens it
+// in the editor. A file that couldn't be read is left as is for fixing.
+func (tt *TimeTracker) editDataFile() (*exec.Cmd, error) {
+	if tt.corrupt == nil {
+		if _, err := tt.backupDataFile(); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return tt.config.editorCommand(tt.config.DataFile), nil
+}
+
+// finishEdit reloads the data file once the editor exited with err
+func (tt *TimeTracker) finishEdit(err error) error {
+	if err != nil {
+		return fmt.Errorf("editor: %v", err)
+	}
+	tt.loadEntries()
+	if tt.corrupt != nil {
+		return fmt.Errorf("%v\n%s", tt.corrupt, tt.recoveryNote())
+	}
+	tt.audit("edit-file", tt.config.DataFile)
+	return nil
+}
+
+// setAsideCorrupt moves a data file that couldn't be read out of the way,
+// once confirmOverwrite agrees, so the next save starts a fresh one. It
+// returns where the damaged file went.
+func (tt *TimeTracker) setAsideCorrupt() (string, error) {
+	if tt.confirmOverwrite == nil || !tt.confirmOverwrite() {
+		return "", fmt.Errorf("%s couldn't be read, so it isn't saved over without your confirmation", tt.config.DataFile)
+	}
+	aside := tt.config.DataFile + ".corrupt-" + time.Now().Format("20060102-150405")
+	if err := os.Rename(tt.config.DataFile, aside); err != nil {
+		return "", err
+	}
+	tt.corrupt = nil
+	return aside, nil
+}
+
+// companionFile names a file kept next to the data file: name itself for
+// entries.json, <data file name>.name for any other data file
+func (tt *TimeTracker) companionFile(name string) string {
+	dir, base := filepath.Split(tt.config.DataFile)
+	if base == "entries.json" {
+		return filepath.Join(dir, name)
+	}
+	return filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base))+"."+name)
+}
+
+// daysFile is where day-level metadata lives
+func (tt *TimeTracker) daysFile() string {
+	return tt.companionFile("days.json")
+}
+
+// auditFile is the append-only log of changes written when AuditLog is on
+func (tt *TimeTracker) auditFile() string {
+	return tt.companionFile("audit.log")
+}
+
+// audit appends a line recording a change to the audit log. A failure to
+// write it is ignored: the change itself has already been saved.
+func (tt *TimeTracker) audit(action, summary string) {
+	if !tt.config.AuditLog {
+		return
+	}
+	f, err := os.OpenFile(tt.auditFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s  %-9s  %s\n", time.Now().Format("2006-01-02 15:04:05"), action, summary)
+}
+
+// printAudit prints the audit log as written
+func printAudit(tracker *TimeTracker) error {
+	data, err := os.ReadFile(tracker.auditFile())
+	if errors.Is(err, os.ErrNotExist) {
+		if !tracker.config.AuditLog {
+			fmt.Println("No audit log. Set audit_log in config to start recording changes.")
+		} else {
+			fmt.Println("No changes recorded yet.")
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// entrySummary describes an entry in one line for the audit log
+func entrySummary(entry Entry) string {
+	summary := entry.Timestamp.Format("2006-01-02 15:04") + "  " + entry.Name
+	if entry.Comment != "" {
+		summary += " (" + strings.ReplaceAll(entry.Comment, "\n", " ") + ")"
+	}
+	return summary
+}
+
+// defaultProfile names the data file set by data_file
+const defaultProfile = "default"
+
+// profile is a named data file, with today's work for a quick overview
+type profile struct {
+	Name     string
+	DataFile string
+	Active   bool
+	Today    time.Duration
+}
+
+// checkProfileName refuses names that can't be a directory of their own
+func checkProfileName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name '%s'", name)
+	}
+	return nil
+}
+
+// activeProfile names the profile whose data file is loaded
+func (tt *TimeTracker) activeProfile() string {
+	if tt.config.Profile == "" {
+		return defaultProfile
+	}
+	return tt.config.Profile
+}
+
+// profileNames lists "default", then by name the configured profiles and
+// those with a directory of their own next to config.json
+func (tt *TimeTracker) profileNames() []string {
+	seen := map[string]bool{defaultProfile: true}
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range tt.config.Profiles {
+		add(name)
+	}
+	dirs, _ := os.ReadDir(filepath.Dir(configFile()))
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(configFile()), dir.Name(), "entries.json")); dir.IsDir() && err == nil {
+			add(dir.Name())
+		}
+	}
+	add(tt.activeProfile())
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...)
+}
+
+// profiles lists the profiles from profileNames, loading each to total
+// today's work
+func (tt *TimeTracker) profiles() []profile {
+	active := tt.activeProfile()
+	
+	var profiles []profile
+	for _, name := range tt.profileNames() {
+		other := &TimeTracker{config: tt.config}
+		other.config.DataFile = tt.profileDataFile(name)
+		other.loadEntries()
+		profiles = append(profiles, profile{
+			Name:     name,
+			DataFile: other.config.DataFile,
+			Active:   name == active,
+			Today:    computeStats(other.getTodaysActivities()).WorkTime,
+		})
+	}
+	return profiles
+}
+
+// profileDataFile resolves a profile name to its data file: the one set in
+// profiles, or else <profile>/entries.json next to config.json
+func (tt *TimeTracker) profileDataFile(name string) string {
+	if path, ok := tt.config.Profiles[name]; ok {
+		return path
+	}
+	if name == "" || name == defaultProfile {
+		return tt.defaultDataFile
+	}
+	return filepath.Join(filepath.Dir(configFile()), name, "entries.json")
+}
+
+// switchProfile makes name the active profile, reloading its entries and
+// recording the choice in config.json without touching the other settings
+func (tt *TimeTracker) switchProfile(name string) error {
+	known := false
+	for _, n := range tt.profileNames() {
+		known = known || n == name
+	}
+	if !known {
+		return fmt.Errorf("unknown profile '%s'", name)
+	}
+	
+	settings := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(configFile()); err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("reading config: %w", err)
+		}
+	}
+	if name == defaultProfile {
+		delete(settings, "profile")
+	} else {
+		settings["profile"], _ = json.Marshal(name)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configFile(), data, 0644); err != nil {
+		return err
+	}
+	
+	tt.config.Profile = name
+	tt.config.DataFile = tt.profileDataFile(name)
+	os.MkdirAll(filepath.Dir(tt.config.DataFile), 0755)
+	tt.loadEntries()
+	tt.loadDays()
+	return nil
+}
+
+// printProfiles lists the profiles with their data files and today's work
+func printProfiles(tracker *TimeTracker) {
+	profiles := tracker.profiles()
+	nameWidth := 0
+	for _, p := range profiles {
+		nameWidth = max(nameWidth, len(p.Name))
+	}
+	
+	fmt.Println("Profiles:")
+	for _, p := range profiles {
+		marker := " "
+		if p.Active {
+			marker = "*"
+		}
+		fmt.Printf("%s %-*s  %s  %s\n", marker, nameWidth, p.Name, tracker.config.display().duration(p.Today), p.DataFile)
+	}
+}
+
+func (tt *TimeTracker) loadDays() {
+	tt.days = make(map[string]DayInfo)
+	if data, err := os.ReadFile(tt.daysFile()); err == nil {
+		json.Unmarshal(data, &tt.days)
+	}
+}
+
+func (tt *TimeTracker) saveDays() error {
+	data, err := json.MarshalIndent(tt.days, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(tt.daysFile(), data, 0644)
+}
+
+// setReflection stores (or with an empty text, clears) the reflection for a day
+func (tt *TimeTracker) setReflection(day time.Time, text string) error {
+	if tt.days == nil {
+		tt.days = make(map[string]DayInfo)
+	}
+	info := tt.days[dayKey(day)]
+	info.Reflection = strings.TrimSpace(text)
+	if info == (DayInfo{}) {
+		delete(tt.days, dayKey(day))
+	} else {
+		tt.days[dayKey(day)] = info
+	}
+	if err := tt.saveDays(); err != nil {
+		return err
+	}
+	tt.audit("reflect", dayKey(day))
+	return nil
+}
+
+// readEntriesFile reads a data file exactly as stored, without sorting
+func readEntriesFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeEntriesFile writes entries to a file other than the data file, such
+// as an archive, as they are
+func writeEntriesFile(path string, entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// hasEntry reports whether an entry with the same timestamp and name exists
+func (tt *TimeTracker) hasEntry(entry Entry) bool {
+	for _, e := range tt.entriesOn(entry.Timestamp) {
+		if e.Timestamp.Equal(entry.Timestamp) && e.Name == entry.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// insertEntry inserts entry into sorted entries, after any with the same timestamp
+func insertEntry(entries []Entry, entry Entry) []Entry {
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Timestamp.After(entry.Timestamp)
+	})
+	entries = append(entries, Entry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
+
+// entriesSorted reports whether entries are in time order
+func entriesSorted(entries []Entry) bool {
+	return sort.SliceIsSorted(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// backupDataFile copies the current data file next to itself before a bulk rewrite
+func (tt *TimeTracker) backupDataFile() (string, error) {
+	// A damaged data file is its own backup, rather than replacing a good .bak
+	if tt.corrupt != nil {
+		return tt.setAsideCorrupt()
+	}
+	data, err := os.ReadFile(tt.config.DataFile)
+	if err != nil {
+		return "", err
+	}
+	backup := tt.config.DataFile + ".bak"
+	return backup, os.WriteFile(backup, data, 0644)
+}
+
+func (tt *TimeTracker) saveEntries() error {
+	tt.dayWork, tt.intervals, tt.dayActivities = nil, nil, nil
+	if tt.corrupt != nil {
+		if _, err := tt.setAsideCorrupt(); err != nil {
+			return err
+		}
+	}
+	
+	// Nothing is printed, since the TUI may be drawing; the audit log says so
+	if !entriesSorted(tt.entries) {
+		sortEntries(tt.entries)
+		defer tt.audit("sort", "entries were out of time order, saved them sorted")
+	}
+	
+	// Ensure directory exists
+	dir := filepath.Dir(tt.config.DataFile)
+	os.MkdirAll(dir, 0755)
+	
+	data, err := json.MarshalIndent(tt.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	
+	// A failed backup mustn't stop the change from being saved
+	tt.backupDaily(time.Now())
+	return writeFileAtomic(tt.config.DataFile, data, 0644)
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so a crash mid-write leaves either the old file or the new
+// one, never a truncated mix. A symlinked path has its target replaced, and
+// an existing file keeps its mode; perm only applies to a new one.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Gone after a successful rename anyway
+	
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// backupDir holds the daily backups of the data file: backups/ next to
+// entries.json, <data file name>.backups/ next to any other data file
+func (tt *TimeTracker) backupDir() string {
+	return tt.companionFile("backups")
+}
+
+// backupName is the daily backup of the data file for day
+func (tt *TimeTracker) backupName(day time.Time) string {
+	base := strings.TrimSuffix(filepath.Base(tt.config.DataFile), filepath.Ext(tt.config.DataFile))
+	return filepath.Join(tt.backupDir(), base+"-"+dayKey(day)+".json")
+}
+
+// backupDaily copies the data file, as it was before today's first change,
+// into the backup directory, then deletes backups older than
+// backup_retention_days
+func (tt *TimeTracker) backupDaily(now time.Time) error {
+	backup := tt.backupName(now)
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(tt.config.DataFile)
+	if err != nil {
+		return err // Nothing saved yet, so nothing to lose
+	}
+	if err := os.MkdirAll(tt.backupDir(), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(backup, data, 0644); err != nil {
+		return err
+	}
+	return tt.pruneBackups(now)
+}
+
+// archiveDir holds the entries moved out of the data file by -archive, one
+// file per month: archive/ next to entries.json, <data file name>.archive/
+// next to any other data file
+func (tt *TimeTracker) archiveDir() string {
+	return tt.companionFile("archive")
+}
+
+// archiveFile is the archive of the month starting at month
+func (tt *TimeTracker) archiveFile(month time.Time) string {
+	return filepath.Join(tt.archiveDir(), month.Format("2006-01")+".json")
+}
+
+// loadArchive reads the archive files of the months touching [start, end) so
+// reports over them see the archived entries, reading each file at most
+// once. Ranges that don't reach back before the data file's first entry,
+// like today or this week, never touch the archive.
+func (tt *TimeTracker) loadArchive(start, end time.Time) {
+	if len(tt.entries) > 0 && !start.Before(tt.entries[0].Timestamp) {
+		return
+	}
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+	for ; month.Before(end); month = month.AddDate(0, 1, 0) {
+		tt.loadArchiveMonth(month)
+	}
+}
+
+// loadAllArchives reads every archive file not read yet
+func (tt *TimeTracker) loadAllArchives() {
+	files, err := os.ReadDir(tt.archiveDir())
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		month, err := time.ParseInLocation("2006-01.json", file.Name(), time.Local)
+		if err == nil {
+			tt.loadArchiveMonth(month)
+		}
+	}
+}
+
+// loadArchiveMonth adds the entries archived for month to tt.archived. A
+// missing or unreadable file counts as read, with nothing in it.
+func (tt *TimeTracker) loadArchiveMonth(month time.Time) {
+	key := month.Format("2006-01")
+	if tt.archivedMonths[key] {
+		return
+	}
+	if tt.archivedMonths == nil {
+		tt.archivedMonths = make(map[string]bool)
+	}
+	tt.archivedMonths[key] = true
+	if entries, err := readEntriesFile(tt.archiveFile(month)); err == nil && len(entries) > 0 {
+		tt.archived = append(tt.archived, entries...)
+		tt.intervals, tt.dayActivities = nil, nil
+		sortEntries(tt.archived)
+	}
+}
+
+// archiveEntries moves the entries before cutoff from the data file into the
+// archive file of their month, returning how many moved. The open of a
+// parallel task or clock-in still running at cutoff stays, so it can still be closed.
+func (tt *TimeTracker) archiveEntries(cutoff time.Time) (int, error) {
+	keep := make(map[string]bool)
+	entryKey := func(e Entry) string {
+		return e.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + e.Name
+	}
+	for _, pairs := range [][2]string{{actionOpen, actionClose}, {actionIn, actionOut}} {
+		closed, running := tt.pairIntervals(pairs[0], pairs[1])
+		for _, interval := range closed {
+			if !interval.close.Timestamp.Before(cutoff) {
+				keep[entryKey(interval.open)] = true
+			}
+		}
+		for _, entry := range running {
+			keep[entryKey(entry)] = true
+		}
+	}
+	
+	var kept []Entry
+	byMonth := make(map[string][]Entry)
+	moved := 0
+	for _, entry := range tt.entries {
+		if !entry.Timestamp.Before(cutoff) || ((entry.Action == actionOpen || entry.Action == actionIn) && keep[entryKey(entry)]) {
+			kept = append(kept, entry)
+			continue
+		}
+		month := entry.Timestamp.Format("2006-01")
+		byMonth[month] = append(byMonth[month], entry)
+		moved++
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+	
+	// Write the archives before the data file loses anything
+	if err := os.MkdirAll(tt.archiveDir(), 0755); err != nil {
+		return 0, err
+	}
+	for month, entries := range byMonth {
+		path := filepath.Join(tt.archiveDir(), month+".json")
+		existing, err := readEntriesFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+		seen := make(map[string]bool)
+		for _, entry := range existing {
+			seen[entryKey(entry)] = true
+		}
+		for _, entry := range entries {
+			if !seen[entryKey(entry)] {
+				existing = append(existing, entry)
+			}
+		}
+		sortEntries(existing)
+		if err := writeEntriesFile(path, existing); err != nil {
+			return 0, err
+		}
+	}
+	
+	previous := tt.entries
+	tt.entries = kept
+	if err := tt.saveEntries(); err != nil {
+		tt.entries = previous
+		return 0, err
+	}
+	tt.archived, tt.archivedMonths = nil, nil
+	tt.audit("archive", fmt.Sprintf("%d entries before %s", moved, dayKey(cutoff)))
+	return moved, nil
+}
+
+// runArchive moves the entries older than archive_after_days out of the data file
+func runArchive(tracker *TimeTracker, now time.Time) error {
+	days := tracker.config.ArchiveAfterDays
+	if days <= 0 {
+		return fmt.Errorf("archive_after_days must be at least 1 (it is %d)", days)
+	}
+	cutoff := startOfDay(now).AddDate(0, 0, -days)
+	
+	backup := "none, data file was empty"
+	if _, err := os.Stat(tracker.config.DataFile); err == nil {
+		if backup, err = tracker.backupDataFile(); err != nil {
+			return fmt.Errorf("backing up data file: %w", err)
+		}
+	}
+	moved, err := tracker.archiveEntries(cutoff)
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		fmt.Printf("Nothing to archive: no entries before %s.\n", dayKey(cutoff))
+		return nil
+	}
+	noun := "entries"
+	if moved == 1 {
+		noun = "entry"
+	}
+	fmt.Printf("%sArchived %d %s from before %s into %s (backup: %s)\n", iconDone, moved, noun, dayKey(cutoff), tracker.archiveDir(), backup)
+	fmt.Printf("%d entries remain in %s\n", len(tracker.entries), tracker.config.DataFile)
+	return nil
+}
+
+// pruneBackups deletes the daily backups from before the retention window
+func (tt *TimeTracker) pruneBackups(now time.Time) error {
+	if tt.config.BackupRetentionDays <= 0 {
+		return nil
+	}
+	oldest := dayKey(startOfDay(now).AddDate(0, 0, -tt.config.BackupRetentionDays))
+	prefix := strings.TrimSuffix(filepath.Base(tt.backupName(now)), dayKey(now)+".json")
+	files, err := os.ReadDir(tt.backupDir())
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		day, ok := strings.CutPrefix(file.Name(), prefix)
+		day, isJSON := strings.CutSuffix(day, ".json")
+		if !ok || !isJSON || len(day) != len("2006-01-02") || day >= oldest {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", day); err == nil {
+			os.Remove(filepath.Join(tt.backupDir(), file.Name()))
+		}
+	}
+	return nil
+}
+
+// entryIndex finds the entry an activity was built from: the entry logged
+// when it ended. Parallel tasks and Start/Stop markers can't be edited.
+func (tt *TimeTracker) entryIndex(activity Activity) (int, error) {
+	if activity.Parallel {
+		return -1, errors.New("parallel tasks can't be edited here")
+	}
+	if activity.Merged {
+		return -1, errors.New("merged rows can't be edited; turn off merge_consecutive to edit each entry")
+	}
+	for i, entry := range tt.entries {
+		if entry.Action != "" || !entry.Timestamp.Equal(activity.End) || tt.config.parser().parseName(entry.Name).Name != activity.Name {
+			continue
+		}
+		if entry.Name == "Start" || entry.Name == "Stop" {
+			return -1, fmt.Errorf("%s markers can't be edited", entry.Name)
+		}
+		return i, nil
+	}
+	return -1, errors.New("entry not found")
+}
+
+// editEntry renames the entry at index and replaces its comment. @key=value
+// tokens in the name are added to the entry's metadata, and a name whose
+// type markers changed drops any explicit type, so the new markers count.
+func (tt *TimeTracker) editEntry(index int, name, comment string) error {
+	names := tt.config.parser()
+	name, meta := splitMeta(strings.TrimSpace(name))
+	name, tags := splitTags(name)
+	if !names.hasTaskName(name) {
+		return errors.New("task name cannot be empty")
+	}
+	if name == "Start" || name == "Stop" {
+		return fmt.Errorf("'%s' is reserved for day markers", name)
+	}
+	previous := tt.entries[index]
+	tt.entries[index].Name = name
+	tt.entries[index].Tags = tags
+	tt.entries[index].Comment = comment
+	if names.parseName(name).Type != names.parseName(previous.Name).Type {
+		tt.entries[index].Type = ""
+	}
+	if len(meta) > 0 {
+		merged := make(map[string]string, len(previous.Meta)+len(meta))
+		for k, v := range previous.Meta {
+			merged[k] = v
+		}
+		for k, v := range meta {
+			merged[k] = v
+		}
+		tt.entries[index].Meta = merged
+	}
+	if err := tt.saveEntries(); err != nil {
+		tt.entries[index] = previous
+		return err
+	}
+	tt.audit("edit", entrySummary(previous)+" -> "+entrySummary(tt.entries[index]))
+	return nil
+}
+
+// splitEntry splits the activity from start to the entry at index in two,
+// adding a copy of the entry without its comment offset after start. The
+// offset must leave both parts at least a minute long.
+func (tt *TimeTracker) splitEntry(index int, start time.Time, offset time.Duration) error {
+	entry := tt.entries[index]
+	boundary := start.Add(offset)
+	if offset < time.Minute || entry.Timestamp.Sub(boundary) < time.Minute {
+		return fmt.Errorf("split between 1 and %d minutes in", int(entry.Timestamp.Sub(start).Minutes())-1)
+	}
+	
+	first := entry
+	first.Timestamp = boundary
+	first.Comment = ""
+	first.Tags = append([]string(nil), entry.Tags...)
+	if entry.Meta != nil {
+		first.Meta = make(map[string]string, len(entry.Meta))
+		for k, v := range entry.Meta {
+			first.Meta[k] = v
+		}
+	}
+	
+	previous := append([]Entry(nil), tt.entries...)
+	tt.entries = insertEntry(tt.entries, first)
+	if err := tt.saveEntries(); err != nil {
+		tt.entries = previous
+		return err
+	}
+	tt.audit("split", entrySummary(entry)+" at "+tt.config.display().clock(boundary))
+	return nil
+}
+
+// lastTaskIndex is the index of the latest task on the timeline, or an error
+// when there is none or the day ended with a Start or Stop
+func (tt *TimeTracker) lastTaskIndex() (int, error) {
+	for i := len(tt.entries) - 1; i >= 0; i-- {
+		entry := tt.entries[i]
+		if entry.Action != "" {
+			continue
+		}
+		if entry.Name == "Start" || entry.Name == "Stop" {
+			return -1, fmt.Errorf("the last entry is %s, log a task first", entry.Name)
+		}
+		return i, nil
+	}
+	return -1, errors.New("there are no entries")
+}
+
+// addNote appends text to the comment of the latest task, after "; " when it
+// already has one, and returns the updated entry
+func (tt *TimeTracker) addNote(text string) (Entry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Entry{}, errors.New("the note is empty")
+	}
+	index, err := tt.lastTaskIndex()
+	if err != nil {
+		return Entry{}, err
+	}
+	previous := tt.entries[index]
+	if previous.Comment != "" {
+		text = previous.Comment + "; " + text
+	}
+	tt.entries[index].Comment = text
+	if err := tt.saveEntries(); err != nil {
+		tt.entries[index] = previous
+		return Entry{}, err
+	}
+	tt.audit("note", entrySummary(tt.entries[index]))
+	return tt.entries[index], nil
+}
+
+// latestEntry is the most recent entry of any kind
+func (tt *TimeTracker) latestEntry() (Entry, bool) {
+	if len(tt.entries) == 0 {
+		return Entry{}, false
+	}
+	return tt.entries[len(tt.entries)-1], true
+}
+
+// undoLast removes the most recent entry and saves, returning what it removed
+func (tt *TimeTracker) undoLast() (Entry, error) {
+	last, ok := tt.latestEntry()
+	if !ok {
+		return last, errors.New("nothing to undo: there are no entries")
+	}
+	tt.entries = tt.entries[:len(tt.entries)-1]
+	if err := tt.saveEntries(); err != nil {
+		tt.entries = append(tt.entries, last)
+		return last, err
+	}
+	tt.undone++
+	tt.audit("undo", entrySummary(last))
+	return last, nil
+}
+
+// addEntry inserts an entry in time order and saves, auditing it under action
+func (tt *TimeTracker) addEntry(action string, entry Entry) error {
+	tt.entries = insertEntry(tt.entries, entry)
+	if err := tt.saveEntries(); err != nil {
+		return err
+	}
+	tt.audit(action, entrySummary(entry))
+	return nil
+}
+
+// parseEntryTime reads when a task finished: "15:04" or a clock time in
+// time_format on the day of now, or a full RFC3339 time. Times after now are
+// refused.
+func parseEntryTime(show displayFormats, value string, now time.Time) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		clock, clockErr := time.Parse("15:04", value)
+		if clockErr != nil {
+			clock, clockErr = time.Parse(show.clockLayout, value)
+		}
+		if clockErr != nil {
+			return t, fmt.Errorf("invalid time %q (use HH:MM or RFC3339)", value)
+		}
+		t = startOfDay(now).Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+	}
+	if t.After(now) {
+		return t, fmt.Errorf("%s is in the future", t.Format("2006-01-02 15:04"))
+	}
+	return t, nil
+}
+
+// checkBackdate refuses, unless force, a task time earlier than the last
+// entry, since it would split an activity that was already logged
+func (tt *TimeTracker) checkBackdate(t time.Time, force bool) error {
+	show := tt.config.display()
+	last, ok := tt.lastEntry()
+	if force || !ok || !t.Before(last.Timestamp) {
+		return nil
+	}
+	return fmt.Errorf("%s is before the last entry (%s at %s)",
+		show.clock(t), last.Name, show.clock(last.Timestamp))
+}
+
+// logTask adds a task the user just finished and returns how many times to
+// ring the bell for it, none unless bell_on_complete is set
+func (tt *TimeTracker) logTask(entry Entry) (int, error) {
+	if !tt.config.BellOnComplete {
+		return 0, tt.addTask(entry)
+	}
+	before := tt.weekWork(entry.Timestamp)
+	if err := tt.addTask(entry); err != nil {
+		return 0, err
+	}
+	return tt.config.completionBells(before, tt.weekWork(entry.Timestamp)), nil
+}
+
+// idleGap is the time since the entry before entry, reported when it exceeds
+// idle_threshold_minutes and would otherwise all count toward entry. Nothing
+// is reported after a Stop or for an ignored entry, and the first task of a
+// day is measured from the Start auto_start_day puts in front of it, or not
+// at all unless it carries on a task from last night.
+func (tt *TimeTracker) idleGap(entry Entry) (time.Duration, bool) {
+	threshold := time.Duration(tt.config.IdleThresholdMinutes) * time.Minute
+	if threshold <= 0 || tt.config.parser().parseActivity(entry, entry.Timestamp, entry.Timestamp, false).Type == Ignored {
+		return 0, false
+	}
+	last, ok := tt.autoStart(entry)
+	if !ok {
+		last, ok = tt.lastEntryBefore(entry.Timestamp)
+	}
+	if !ok || last.Name == "Stop" {
+		return 0, false
+	}
+	if last.Timestamp.Before(tt.todayStart(entry.Timestamp)) && !tt.overnight(entry) {
+		return 0, false
+	}
+	gap := entry.Timestamp.Sub(last.Timestamp)
+	return gap, gap > threshold
+}
+
+// splitIdle adds an ignored "Idle" entry idle_threshold_minutes before entry,
+// so entry keeps only that much of a long gap and the rest is ignored
+func (tt *TimeTracker) splitIdle(entry Entry) error {
+	idle := Entry{
+		Timestamp: entry.Timestamp.Add(-time.Duration(tt.config.IdleThresholdMinutes) * time.Minute),
+		Name:      "Idle",
+		Type:      tt.config.parser().explicitType("Idle", Ignored),
+	}
+	return tt.addEntry("idle", idle)
+}
+
+// idleQuestion asks whether to split a gap off the task just logged
+func idleQuestion(show displayFormats, gap time.Duration) string {
+	return fmt.Sprintf("Nothing was logged for %s before this task. Split that off as idle time?", show.span(gap))
+}
+
+// weekWork is the work logged in the week containing t
+func (tt *TimeTracker) weekWork(t time.Time) time.Duration {
+	return computeStats(tt.getActivitiesBetween(startOfWeek(t, tt.config.weekStartDay()), t)).WorkTime
+}
+
+// completionBells is how many times to ring for a logged task that took the
+// week's work from before to after: none with bell_on_complete off, two when
+// that met weekly_goal_hours, otherwise one
+func (c Config) completionBells(before, after time.Duration) int {
+	if !c.BellOnComplete {
+		return 0
+	}
+	if goal := hoursDuration(c.WeeklyGoalHours); goal > 0 && before < goal && after >= goal {
+		return 2
+	}
+	return 1
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than a pipe or file
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// cliColor is whether CLI output is coloured: stdout is a terminal and
+// NO_COLOR isn't set
+var cliColor bool
+
+// paint renders CLI text in style, or leaves it plain when cliColor is off.
+// Lines are styled one by one so they aren't padded to a common width.
+func paint(style lipgloss.Style, text string) string {
+	if !cliColor {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = style.Render(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// longTask reports when the task running since the last entry started and
+// how long it has run, and whether that's past long_task_minutes. An entry
+// from before today isn't running anymore, whatever came after it.
+func (tt *TimeTracker) longTask(now time.Time) (time.Time, time.Duration, bool) {
+	limit := time.Duration(tt.config.LongTaskMinutes) * time.Minute
+	last, ok := tt.lastEntry()
+	if limit <= 0 || !ok || last.Name == "Stop" || last.Timestamp.Before(tt.todayStart(now)) {
+		return time.Time{}, 0, false
+	}
+	elapsed := now.Sub(last.Timestamp)
+	return last.Timestamp, elapsed, elapsed > limit
+}
+
+// notify shows a desktop notification with notify-send on Linux or
+// osascript on macOS
+func notify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("notify-send", title, body)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", body, title))
+	default:
+		return fmt.Errorf("no desktop notifications on %s", runtime.GOOS)
+	}
+	return cmd.Run()
+}
+
+// bell rings the terminal bell n times off the update loop. A lone BEL can't
+// garble the screen even amid the renderer's output, as terminals act on it
+// without ending an escape sequence.
+func bell(n int) tea.Cmd {
+	return func() tea.Msg {
+		ringBell(n)
+		return nil
+	}
+}
+
+// ringBell rings the terminal bell n times, unless stdout isn't a terminal
+func ringBell(n int) {
+	if n == 0 || !stdoutIsTerminal() {
+		return
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(200 * time.Millisecond) // Back-to-back bells sound like one
+		}
+		fmt.Print("\a")
+	}
+}
+
+// addTask records a completed task. With AutoStartDay on, the first task of a
+// day gets a Start inserted ahead of it so its duration is properly bounded.
+func (tt *TimeTracker) addTask(entry Entry) error {
+	if start, ok := tt.autoStart(entry); ok {
+		tt.entries = insertEntry(tt.entries, start)
+	}
+	return tt.addEntry("add", entry)
+}
+
+// autoStart is the Start addTask puts in front of entry when auto_start_day
+// is on and entry is the first of its day
+func (tt *TimeTracker) autoStart(entry Entry) (Entry, bool) {
+	dayStart := tt.todayStart(entry.Timestamp)
+	if !tt.config.AutoStartDay || len(tt.entriesIn(dayStart, dayStart.AddDate(0, 0, 1))) > 0 {
+		return Entry{}, false
+	}
+	startTime := dayStart
+	if tt.config.AutoStartMinutes > 0 {
+		startTime = entry.Timestamp.Add(-time.Duration(tt.config.AutoStartMinutes) * time.Minute)
+		if startTime.Before(dayStart) {
+			startTime = dayStart
+		}
+	}
+	return Entry{Timestamp: startTime, Name: "Start"}, true
+}
+
+// addStart starts the day at now. Unless force, a day that already has a
+// Start is refused, except to start again after it was ended with a Stop.
+func (tt *TimeTracker) addStart(now time.Time, force bool) error {
+	today := tt.todayStart(now)
+	if last, ok := tt.lastEntry(); !force && !(ok && last.Name == "Stop") {
+		for _, entry := range tt.entriesIn(today, today.AddDate(0, 0, 1)) {
+			if entry.Name == "Start" {
+				return fmt.Errorf("day already started at %s", tt.config.display().clock(entry.Timestamp))
+			}
+		}
+	}
+	entry := Entry{
+		Timestamp: now,
+		Name:      "Start",
+	}
+	return tt.addEntry("start", entry)
+}
+
+// addStop ends the day at now with a Stop entry, which reports treat as a
+// boundary like Start. A day that already ended is refused.
+func (tt *TimeTracker) addStop(now time.Time) error {
+	if last, ok := tt.lastEntry(); ok && last.Name == "Stop" {
+		return fmt.Errorf("the day already ended at %s", tt.config.display().clock(last.Timestamp))
+	}
+	return tt.addEntry("stop", Entry{Timestamp: now, Name: "Stop"})
+}
+
+// lastEntry returns the most recent entry on the sequential timeline,
+// skipping parallel open/close markers
+func (tt *TimeTracker) lastEntry() (Entry, bool) {
+	return tt.lastEntryBefore(time.Time{})
+}
+
+// lastEntryBefore returns the latest timeline entry strictly before t, or the
+// latest overall when t is zero. Archived entries loaded for a report only
+// count for a given t, as they all precede the data file's timeline.
+func (tt *TimeTracker) lastEntryBefore(t time.Time) (Entry, bool) {
+	if entry, ok := lastTimelineEntry(tt.entries, t); ok || t.IsZero() {
+		return entry, ok
+	}
+	return lastTimelineEntry(tt.archived, t)
+}
+
+// lastTimelineEntry returns the latest of sorted entries strictly before t
+// (any when t is zero), skipping parallel open/close markers
+func lastTimelineEntry(entries []Entry, t time.Time) (Entry, bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Action != "" || (!t.IsZero() && !entry.Timestamp.Before(t)) {
+			continue
+		}
+		return entry, true
+	}
+	return Entry{}, false
+}
+
+// expandShortcuts replaces every @alias word of name configured in shortcuts
+// with its text. Words naming no shortcut stay as typed.
+func (c Config) expandShortcuts(name string) string {
+	words := strings.Split(name, " ")
+	for i, word := range words {
+		if !strings.HasPrefix(word, "@") {
+			continue
+		}
+		if full, ok := c.Shortcuts[word[1:]]; ok {
+			words[i] = full
+		} else if full, ok := c.Shortcuts[word]; ok {
+			words[i] = full
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// quickTask returns the name of configured quick task n (1-based)
+func (tt *TimeTracker) quickTask(n int) (string, error) {
+	if len(tt.config.QuickTasks) == 0 {
+		return "", fmt.Errorf("no quick tasks configured (set quick_tasks in config)")
+	}
+	if n < 1 || n > len(tt.config.QuickTasks) {
+		return "", fmt.Errorf("no quick task %d (1-%d configured)", n, len(tt.config.QuickTasks))
+	}
+	return tt.config.QuickTasks[n-1], nil
+}
+
+// openParallel starts a named task running alongside the timeline
+func (tt *TimeTracker) openParallel(name, comment string) error {
+	if !tt.config.ParallelMode {
+		return fmt.Errorf("parallel tasks are disabled (set parallel_mode in config)")
+	}
+	_, running := tt.parallelIntervals()
+	for _, entry := range running {
+		if entry.Name == name {
+			return fmt.Errorf("'%s' is already running since %s", name, tt.config.display().clock(entry.Timestamp))
+		}
+	}
+	return tt.addEntry("open", Entry{Timestamp: time.Now(), Name: name, Comment: comment, Action: actionOpen})
+}
+
+// closeParallel stops a running parallel task and returns how long it ran
+func (tt *TimeTracker) closeParallel(name string) (time.Duration, error) {
+	if !tt.config.ParallelMode {
+		return 0, fmt.Errorf("parallel tasks are disabled (set parallel_mode in config)")
+	}
+	_, running := tt.parallelIntervals()
+	for i := len(running) - 1; i >= 0; i-- {
+		if running[i].Name == name {
+			now := time.Now()
+			return now.Sub(running[i].Timestamp), tt.addEntry("close", Entry{Timestamp: now, Name: name, Action: actionClose})
+		}
+	}
+	return 0, fmt.Errorf("no running parallel task named '%s'", name)
+}
+
+// clockIn starts an explicit in/out interval for name, clocking out of the
+// task currently clocked in first
+func (tt *TimeTracker) clockIn(name, comment string) error {
+	name, tags := splitTags(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("task name is empty")
+	}
+	now := time.Now()
+	_, running := tt.clockIntervals()
+	for _, entry := range running {
+		if entry.Name == name {
+			return fmt.Errorf("already clocked in to '%s' since %s", name, tt.config.display().clock(entry.Timestamp))
+		}
+	}
+	for _, entry := range running {
+		if err := tt.addEntry("out", Entry{Timestamp: now, Name: entry.Name, Action: actionOut}); err != nil {
+			return err
+		}
+	}
+	return tt.addEntry("in", Entry{Timestamp: now, Name: name, Comment: comment, Action: actionIn, Tags: tags})
+}
+
+// clockOut ends the running clocked task and returns it with how long it ran
+func (tt *TimeTracker) clockOut() (Entry, time.Duration, error) {
+	_, running := tt.clockIntervals()
+	if len(running) == 0 {
+		return Entry{}, 0, errors.New("not clocked in")
+	}
+	now := time.Now()
+	for _, entry := range running {
+		if err := tt.addEntry("out", Entry{Timestamp: now, Name: entry.Name, Action: actionOut}); err != nil {
+			return Entry{}, 0, err
+		}
+	}
+	last := running[len(running)-1]
+	return last, now.Sub(last.Timestamp), nil
+}
+
+// extend repeats the last entry at the current time. Unless forced, it refuses
+// when the last entry is too fresh to produce a meaningful activity.
+func (tt *TimeTracker) extend(force bool) error {
+	entry, _, err := tt.extendEntry(force, time.Now())
+	if err != nil {
+		return err
+	}
+	return tt.addEntry("extend", entry)
+}
+
+// extendEntry returns the entry extending at now would add, and when the
+// activity it ends starts: the last entry, or the start of today if that
+// was on an earlier day
+func (tt *TimeTracker) extendEntry(force bool, now time.Time) (Entry, time.Time, error) {
+	lastEntry, ok := tt.lastEntry()
+	if !ok {
+		return Entry{}, time.Time{}, fmt.Errorf("no entries to extend")
+	}
+	
+	if lastEntry.Name == "Start" {
+		return Entry{}, time.Time{}, fmt.Errorf("cannot extend start entry")
+	}
+	if lastEntry.Name == "Stop" {
+		return Entry{}, time.Time{}, fmt.Errorf("the day was closed at %s, log a new task instead", tt.config.display().clock(lastEntry.Timestamp))
+	}
+	
+	minGap := time.Duration(tt.config.MinExtendMinutes) * time.Minute
+	if age := now.Sub(lastEntry.Timestamp); !force && age < minGap {
+		return Entry{}, time.Time{}, fmt.Errorf("last entry is only %s old, nothing to extend yet", age.Round(time.Second))
+	}
+	
+	entry := Entry{
+		Timestamp: now,
+		Name:      lastEntry.Name,
+		Comment:   lastEntry.Comment,
+		Type:      lastEntry.Type,
+		Meta:      lastEntry.Meta,
+	}
+	
+	from := lastEntry.Timestamp
+	if dayStart := tt.todayStart(now); from.Before(dayStart) {
+		from = dayStart
+	}
+	return entry, from, nil
+}
+
+// extendPreview describes what extending to entry would log
+func extendPreview(show displayFormats, entry Entry, from time.Time) string {
+	return fmt.Sprintf("Extend '%s' to now? This activity becomes %s (from %s).",
+		entry.Name, show.span(entry.Timestamp.Sub(from)), show.clock(from))
+}
+
+func (tt *TimeTracker) getCurrentStatus() string {
+	show := tt.config.display()
+	lastEntry, ok := tt.lastEntry()
+	if !ok {
+		return infoStyle.Render("No activities yet. Start your day!")
+	}
+	
+	duration := time.Since(lastEntry.Timestamp)
+	
+	var status string
+	if lastEntry.Name == "Stop" {
+		ended := show.clock(lastEntry.Timestamp)
+		if lastEntry.Timestamp.Before(tt.todayStart(time.Now())) {
+			ended = lastEntry.Timestamp.Format("2006-01-02") + " " + ended
+		}
+		status = infoStyle.Render("Day ended at " + ended)
+	} else if lastEntry.Name == "Start" {
+		status = currentActivityStyle.Render(fmt.Sprintf("Day started (%s)", 
+			humanizeSince(duration)))
+	} else {
+		status = currentActivityStyle.Render(fmt.Sprintf("Latest: %s (%s)", 
+			lastEntry.Name, humanizeSince(duration)))
+	}
+	
+	// Clocked in since -in
+	_, clocked := tt.clockIntervals()
+	for _, entry := range clocked {
+		status += "\n" + workStyle.Render(fmt.Sprintf("Clocked in: %s (%s)",
+			entry.Name, show.span(time.Since(entry.Timestamp))))
+	}
+	
+	// Parallel tasks still running
+	if tt.config.ParallelMode {
+		_, running := tt.parallelIntervals()
+		for _, entry := range running {
+			status += "\n" + workStyle.Render(fmt.Sprintf("Running: %s (%s)",
+				entry.Name, show.span(time.Since(entry.Timestamp))))
+		}
+	}
+	return status
+}
+
+// staleClosures returns the entries that would close state left open on a day
+// before now's: a Stop after the last timeline entry, and a close for every
+// parallel task or clock-out for every clock-in still running since then. Each goes at AutoCloseAtHour on its
+// day, or right at the entry when that was logged later.
+func (tt *TimeTracker) staleClosures(now time.Time) []Entry {
+	if tt.config.AutoCloseAtHour <= 0 {
+		return nil
+	}
+	today := startOfDay(now)
+	closeAt := func(t time.Time) time.Time {
+		end := startOfDay(t).Add(time.Duration(tt.config.AutoCloseAtHour) * time.Hour)
+		if t.After(end) {
+			return t
+		}
+		return end
+	}
+	
+	// A task started late last night may simply still be going
+	var closures []Entry
+	if last, ok := tt.lastEntry(); ok && last.Name != "Stop" && last.Timestamp.Before(today) && now.Sub(last.Timestamp) > tt.config.overnightGap() {
+		closures = append(closures, Entry{Timestamp: closeAt(last.Timestamp), Name: "Stop"})
+	}
+	_, running := tt.parallelIntervals()
+	for _, entry := range running {
+		if entry.Timestamp.Before(today) {
+			closures = append(closures, Entry{Timestamp: closeAt(entry.Timestamp), Name: entry.Name, Action: actionClose})
+		}
+	}
+	_, clocked := tt.clockIntervals()
+	for _, entry := range clocked {
+		if entry.Timestamp.Before(today) {
+			closures = append(closures, Entry{Timestamp: closeAt(entry.Timestamp), Name: entry.Name, Action: actionOut})
+		}
+	}
+	return closures
+}
+
+// closeStale records the entries returned by staleClosures
+func (tt *TimeTracker) closeStale(closures []Entry) error {
+	tt.entries = append(tt.entries, closures...)
+	sortEntries(tt.entries)
+	if err := tt.saveEntries(); err != nil {
+		return err
+	}
+	for _, entry := range closures {
+		tt.audit("close-day", entrySummary(entry))
+	}
+	return nil
+}
+
+// describeClosures explains each closure in a line, e.g. "2024-01-15 left open, closing at 18:00"
+func describeClosures(show displayFormats, closures []Entry) []string {
+	var lines []string
+	for _, entry := range closures {
+		when := entry.Timestamp.Format("2006-01-02") + " at " + show.clock(entry.Timestamp)
+		if entry.Action == actionClose {
+			lines = append(lines, fmt.Sprintf("'%s' still running, closing %s", entry.Name, when))
+		} else if entry.Action == actionOut {
+			lines = append(lines, fmt.Sprintf("Still clocked in to '%s', clocking out %s", entry.Name, when))
+		} else {
+			lines = append(lines, fmt.Sprintf("Day left open, closing %s", when))
+		}
+	}
+	return lines
+}
+
+func recentActivities(activities []Activity, limit int) []Activity {
+	if len(activities) > limit {
+		return activities[len(activities)-limit:]
+	}
+	return activities
+}
+
+// entriesOn returns the entries logged on the calendar day containing t
+func (tt *TimeTracker) entriesOn(t time.Time) []Entry {
+	dayStart := startOfDay(t)
+	return tt.entriesIn(dayStart, dayStart.AddDate(0, 0, 1))
+}
+
+// entriesIn returns the entries logged in [dayStart, dayEnd), archived ones
+// included
+func (tt *TimeTracker) entriesIn(dayStart, dayEnd time.Time) []Entry {
+	entries := entriesBetween(tt.entries, dayStart, dayEnd)
+	if archived := entriesBetween(tt.archived, dayStart, dayEnd); len(archived) > 0 {
+		entries = append(append([]Entry{}, archived...), entries...)
+		sortEntries(entries)
+	}
+	return entries
+}
+
+// entriesBetween returns the run of sorted entries that falls in [start, end)
+func entriesBetween(entries []Entry, start, end time.Time) []Entry {
+	first := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].Timestamp.Before(start)
+	})
+	last := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].Timestamp.Before(end)
+	})
+	return entries[first:last]
+}
+
+// pace projects when today's work reaches daily_target_hours if it keeps
+// going at the rate so far: work done over the time since the first entry
+func (tt *TimeTracker) pace(now time.Time) string {
+	start := tt.todayStart(now)
+	entries := tt.entriesIn(start, start.AddDate(0, 0, 1))
+	if len(entries) == 0 {
+		return "Nothing logged today yet."
+	}
+	work := computeStats(tt.activitiesOfDay(start)).WorkTime
+	return paceNote(tt.config.display(), work, now.Sub(entries[0].Timestamp), hoursDuration(tt.config.DailyTargetHours), now, start.AddDate(0, 0, 1))
+}
+
+// paceNote describes the projection for work done in elapsed toward goal,
+// for a day that ends at end
+func paceNote(show displayFormats, work, elapsed, goal time.Duration, now, end time.Time) string {
+	if work >= goal {
+		return fmt.Sprintf("Goal met: %s of %s today.", show.duration(work), show.duration(goal))
+	}
+	if work <= 0 || elapsed <= 0 {
+		return fmt.Sprintf("No work logged yet today, so there's no pace to project toward %s.", show.duration(goal))
+	}
+	eta := now.Add(time.Duration(float64(goal-work) * float64(elapsed) / float64(work)))
+	if !eta.Before(end) {
+		return fmt.Sprintf("At current pace you won't hit %s today (%s to go).", show.duration(goal), show.duration(goal-work))
+	}
+	return fmt.Sprintf("At current pace you'll hit %s by %s.", show.duration(goal), show.clock(eta))
+}
+
+// workdaySpan is the time from the first to the last entry of the day
+// beginning at start
+func (tt *TimeTracker) workdaySpan(start time.Time) time.Duration {
+	entries := tt.entriesIn(start, start.AddDate(0, 0, 1))
+	if len(entries) < 2 {
+		return 0
+	}
+	return entries[len(entries)-1].Timestamp.Sub(entries[0].Timestamp)
+}
+
+// longDayNote is a gentle suggestion to pause when the span of the day
+// beginning at start goes past max_workday_hours, or "" when it doesn't
+func (tt *TimeTracker) longDayNote(start time.Time) string {
+	show := tt.config.display()
+	span := tt.workdaySpan(start)
+	if tt.config.MaxWorkdayHours <= 0 || span <= hoursDuration(tt.config.MaxWorkdayHours) {
+		return ""
+	}
+	if now := time.Now(); !now.Before(start) && now.Before(start.AddDate(0, 0, 1)) {
+		return fmt.Sprintf("You've been at it for %s today. Maybe time for a break, or to call it a day?", show.duration(span))
+	}
+	return fmt.Sprintf("This day spanned %s from first to last entry.", show.duration(span))
+}
+
+func (tt *TimeTracker) getTodaysActivities() []Activity {
+	return tt.activitiesOfDay(tt.todayStart(time.Now()))
+}
+
+// activitiesOfDay builds the activities of the day beginning at start, which
+// runs to the same time tomorrow
+func (tt *TimeTracker) activitiesOfDay(start time.Time) []Activity {
+	return tt.activitiesIn(start, start.AddDate(0, 0, 1))
+}
+
+// activitiesIn builds the activities in [start, end), clipping those that
+// cross a bound other than midnight, such as a day_start_hour
+func (tt *TimeTracker) activitiesIn(start, end time.Time) []Activity {
+	if start.Equal(startOfDay(start)) && end.Equal(startOfDay(end)) {
+		return tt.getActivitiesBetween(start, end)
+	}
+	return clipActivities(tt.getActivitiesBetween(start, end), start, end)
+}
+
+// todayStart is when the day containing now began: day_start_hour today, or
+// yesterday when now is earlier than that
+func (tt *TimeTracker) todayStart(now time.Time) time.Time {
+	start := tt.dayStartOn(now)
+	if now.Before(start) {
+		start = tt.dayStartOn(now.AddDate(0, 0, -1))
+	}
+	return start
+}
+
+// dayStartOn is when the day dated date begins: day_start_hour on that date
+func (tt *TimeTracker) dayStartOn(date time.Time) time.Time {
+	hour := tt.config.DayStartHour
+	if hour < 0 || hour > 23 {
+		hour = 0
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, date.Location())
+}
+
+// clipActivities trims activities to [start, end), dropping those outside it
+func clipActivities(activities []Activity, start, end time.Time) []Activity {
+	clipped := []Activity{}
+	for _, activity := range activities {
+		if !activity.End.After(start) || !activity.Start.Before(end) {
+			continue
+		}
+		if activity.Start.Before(start) {
+			activity.Start = start
+		}
+		if activity.End.After(end) {
+			activity.End = end
+		}
+		if d := activity.End.Sub(activity.Start); d < activity.Duration {
+			activity.Duration = d
+			activity.Overlap = min(activity.Overlap, d)
+		}
+		clipped = append(clipped, activity)
+	}
+	return clipped
+}
+
+// getActivitiesBetween builds the activities of every day touching [start, end),
+// one day at a time so range totals always match the per-day reports
+func (tt *TimeTracker) getActivitiesBetween(start, end time.Time) []Activity {
+	activities := []Activity{}
+	for day := startOfDay(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+		activities = append(activities, tt.getDayActivities(day)...)
+	}
+	return activities
+}
+
+// getAllActivities builds the activities of every day with entries
+func (tt *TimeTracker) getAllActivities() []Activity {
+	tt.loadAllArchives()
+	if len(tt.entries) == 0 && len(tt.archived) == 0 {
+		return []Activity{}
+	}
+	var first, last time.Time
+	if len(tt.archived) > 0 {
+		first, last = tt.archived[0].Timestamp, tt.archived[len(tt.archived)-1].Timestamp
+	}
+	if len(tt.entries) > 0 {
+		if first.IsZero() || tt.entries[0].Timestamp.Before(first) {
+			first = tt.entries[0].Timestamp
+		}
+		if end := tt.entries[len(tt.entries)-1].Timestamp; end.After(last) {
+			last = end
+		}
+	}
+	return tt.getActivitiesBetween(first, startOfDay(last).AddDate(0, 0, 1))
+}
+
+// getDayActivities returns the activities logged on the calendar day
+// containing t. Each day is built once until the entries change, since the
+// TUI redraws the same days on every frame; callers get their own copy.
+func (tt *TimeTracker) getDayActivities(t time.Time) []Activity {
+	key := dayKey(t)
+	activities, ok := tt.dayActivities[key]
+	if !ok {
+		activities = tt.buildDayActivities(t)
+		if tt.dayActivities == nil {
+			tt.dayActivities = make(map[string][]Activity)
+		}
+		tt.dayActivities[key] = activities
+	}
+	return append([]Activity{}, activities...)
+}
+
+// buildDayActivities builds the activities logged on the calendar day containing t
+func (tt *TimeTracker) buildDayActivities(t time.Time) []Activity {
+	dayStart := startOfDay(t)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	// Tasks can run across midnight from the day before or into the next
+	tt.loadArchive(dayStart.AddDate(0, 0, -1), dayEnd.AddDate(0, 0, 1))
+	daysEntries := withClockOuts(tt.entriesOn(t))
+	
+	var activities []Activity
+	
+	// Convert entries to activities (each activity represents time between entries)
+	for i := 0; i < len(daysEntries); i++ {
+		entry := daysEntries[i]
+		
+		// Skip start and stop entries - they don't represent completed work.
+		// A clock-out only bounds the task logged after it.
+		if entry.Name == "Start" || entry.Name == "Stop" || entry.Action == actionOut {
+			continue
+		}
+		
+		// Find the previous entry to calculate duration
+		var start time.Time
+		if i == 0 {
+			// Without a preceding entry the first task of the day is bounded
+			// by the day cutoff, unless an explicit Start is required. A task
+			// carried over from last night always counts from midnight.
+			if tt.config.RequireStart && !tt.overnight(entry) {
+				continue
+			}
+			start = dayStart
+		} else {
+			start = daysEntries[i-1].Timestamp
+		}
+		
+		end := entry.Timestamp
+		if tt.tooShort(end.Sub(start)) {
+			continue
+		}
+		
+		activity := tt.activity(entry, start, end)
+		activities = append(activities, activity)
+	}
+	
+	// A task still going at midnight contributes its part before midnight
+	if next, ok := tt.firstEntryFrom(dayEnd); ok && len(daysEntries) > 0 && daysEntries[len(daysEntries)-1].Action != actionOut && tt.overnight(next) {
+		start := daysEntries[len(daysEntries)-1].Timestamp
+		if !tt.tooShort(dayEnd.Sub(start)) {
+			activities = append(activities, tt.activity(next, start, dayEnd))
+		}
+	}
+	
+	activities = tt.withClockedActivities(activities, dayStart, dayEnd)
+	if tt.config.ParallelMode {
+		activities = tt.withParallelActivities(activities, dayStart, dayEnd)
+	}
+	if activities == nil {
+		return []Activity{}
+	}
+	return activities
+}
+
+// overnightGap is the longest gap across midnight still read as one task;
+// anything longer is a night off rather than work
+func (c Config) overnightGap() time.Duration {
+	return time.Duration(max(c.OvernightGapHours, 0)) * time.Hour
+}
+
+// overnight reports whether entry, the first of its day, ends a task that
+// began before midnight: the day before wasn't closed with a Stop and its
+// last entry is within overnight_gap_hours
+func (tt *TimeTracker) overnight(entry Entry) bool {
+	if entry.Name == "Start" || entry.Name == "Stop" {
+		return false
+	}
+	midnight := startOfDay(entry.Timestamp)
+	previous, ok := tt.lastEntryBefore(midnight)
+	if !ok || previous.Name == "Stop" || entry.Timestamp.Sub(previous.Timestamp) > tt.config.overnightGap() {
+		return false
+	}
+	// Clocking out after it ended the evening like a Stop
+	closed, _ := tt.clockIntervals()
+	for _, interval := range closed {
+		if out := interval.close.Timestamp; out.After(previous.Timestamp) && out.Before(midnight) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstEntryFrom returns the earliest timeline entry at or after t
+func (tt *TimeTracker) firstEntryFrom(t time.Time) (Entry, bool) {
+	for _, entries := range [][]Entry{tt.archived, tt.entries} {
+		i := sort.Search(len(entries), func(i int) bool {
+			return !entries[i].Timestamp.Before(t)
+		})
+		for ; i < len(entries); i++ {
+			if entries[i].Action == "" {
+				return entries[i], true
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// tooShort reports whether an activity lasting d falls under DropBelowMinutes
+// and so is left out of reports and totals altogether
+func (tt *TimeTracker) tooShort(d time.Duration) bool {
+	return d < time.Duration(tt.config.DropBelowMinutes)*time.Minute
+}
+
+// roundedDuration rounds d to the nearest multiple of increment, halves
+// rounding up (7m30s becomes 15m at a 15-minute increment)
+func roundedDuration(d, increment time.Duration) time.Duration {
+	if increment <= 0 {
+		return d
+	}
+	return d.Round(increment)
+}
+
+// roundTo is the increment reports round activities to
+func (c Config) roundTo() time.Duration {
+	return time.Duration(c.RoundToMinutes) * time.Minute
+}
+
+// withClockOuts is the timeline with clock-outs kept in, so a task logged
+// after a clocked in/out pair is measured from the clock-out rather than
+// counting the clocked time a second time
+func withClockOuts(entries []Entry) []Entry {
+	var sequential []Entry
+	for _, entry := range entries {
+		if entry.Action == "" || entry.Action == actionOut {
+			sequential = append(sequential, entry)
+		}
+	}
+	return sequential
+}
+
+// timeline filters out parallel open/close markers, leaving the sequential entries
+func timeline(entries []Entry) []Entry {
+	var sequential []Entry
+	for _, entry := range entries {
+		if entry.Action == "" {
+			sequential = append(sequential, entry)
+		}
+	}
+	return sequential
+}
+
+// parallelInterval is one closed open/close or in/out pair
+type parallelInterval struct {
+	open, close Entry
+}
+
+// pairedIntervals is what pairIntervals found for one kind of pair
+type pairedIntervals struct {
+	closed  []parallelInterval
+	running []Entry
+}
+
+// parallelIntervals pairs every close with the most recent unmatched open of
+// the same name. Opens without a close yet are returned as still running.
+func (tt *TimeTracker) parallelIntervals() (closed []parallelInterval, running []Entry) {
+	return tt.pairIntervals(actionOpen, actionClose)
+}
+
+// clockIntervals pairs clock-ins with clock-outs the same way
+func (tt *TimeTracker) clockIntervals() (closed []parallelInterval, running []Entry) {
+	return tt.pairIntervals(actionIn, actionOut)
+}
+
+// pairIntervals pairs every closeAction entry with the most recent unmatched
+// openAction entry of the same name. Pairing walks the whole history, so the
+// result is kept until the entries change; callers must not modify it.
+func (tt *TimeTracker) pairIntervals(openAction, closeAction string) (closed []parallelInterval, running []Entry) {
+	if paired, ok := tt.intervals[openAction]; ok {
+		return paired.closed, paired.running
+	}
+	open := make(map[string][]Entry)
+	for _, entries := range [][]Entry{tt.archived, tt.entries} {
+		for _, entry := range entries {
+			switch entry.Action {
+			case openAction:
+				open[entry.Name] = append(open[entry.Name], entry)
+			case closeAction:
+				if stack := open[entry.Name]; len(stack) > 0 {
+					closed = append(closed, parallelInterval{open: stack[len(stack)-1], close: entry})
+					open[entry.Name] = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+	for _, stack := range open {
+		running = append(running, stack...)
+	}
+	sortEntries(running)
+	if tt.intervals == nil {
+		tt.intervals = make(map[string]pairedIntervals)
+	}
+	tt.intervals[openAction] = pairedIntervals{closed: closed, running: running}
+	return closed, running
+}
+
+// withClockedActivities adds the parts of clocked in/out pairs that fall in
+// [dayStart, dayEnd), keeping activities sorted by start
+func (tt *TimeTracker) withClockedActivities(activities []Activity, dayStart, dayEnd time.Time) []Activity {
+	closed, _ := tt.clockIntervals()
+	added := false
+	for _, interval := range closed {
+		start, end := interval.open.Timestamp, interval.close.Timestamp
+		if !start.Before(dayEnd) || !end.After(dayStart) {
+			continue
+		}
+		if start.Before(dayStart) {
+			start = dayStart
+		}
+		if end.After(dayEnd) {
+			end = dayEnd
+		}
+		if tt.tooShort(end.Sub(start)) {
+			continue
+		}
+		activities = append(activities, tt.activity(interval.open, start, end))
+		added = true
+	}
+	if added {
+		sort.SliceStable(activities, func(i, j int) bool {
+			return activities[i].Start.Before(activities[j].Start)
+		})
+	}
+	return activities
+}
+
+// withParallelActivities adds the parts of parallel tasks that fall in
+// [dayStart, dayEnd) and, when totals count overlap once, marks the time
+// each activity shares with earlier ones so it isn't counted twice
+func (tt *TimeTracker) withParallelActivities(activities []Activity, dayStart, dayEnd time.Time) []Activity {
+	closed, _ := tt.parallelIntervals()
+	for _, interval := range closed {
+		start, end := interval.open.Timestamp, interval.close.Timestamp
+		if !start.Before(dayEnd) || !end.After(dayStart) {
+			continue
+		}
+		if start.Before(dayStart) {
+			start = dayStart
+		}
+		if end.After(dayEnd) {
+			end = dayEnd
+		}
+		if tt.tooShort(end.Sub(start)) {
+			continue
+		}
+		activity := tt.activity(interval.close, start, end)
+		activity.Parallel = true
+		if activity.Comment == "" {
+			activity.Comment = interval.open.Comment
+		}
+		activities = append(activities, activity)
+	}
+	
+	sort.SliceStable(activities, func(i, j int) bool {
+		return activities[i].Start.Before(activities[j].Start)
+	})
+	
+	if tt.config.ParallelTotals != "per-task" {
+		markOverlaps(activities)
+	}
+	return activities
+}
+
+// markOverlaps sets Overlap on each activity (sorted by start) to the time it
+// shares with earlier activities of the same type. Because starts are sorted,
+// only the last span of the covered union can reach into the next activity.
+func markOverlaps(activities []Activity) {
+	covered := make(map[ActivityType][][2]time.Time)
+	for i := range activities {
+		a := &activities[i]
+		spans := covered[a.Type]
+		if n := len(spans); n > 0 && a.Start.Before(spans[n-1][1]) {
+			last := &spans[n-1]
+			a.Overlap = overlap(a.Start, a.End, last[0], last[1])
+			if a.End.After(last[1]) {
+				last[1] = a.End
+			}
+			continue
+		}
+		covered[a.Type] = append(spans, [2]time.Time{a.Start, a.End})
+	}
+}
+
+func (tt *TimeTracker) getTodaysStats() DayStats {
+	return computeStats(tt.getTodaysActivities())
+}
+
+func (tt *TimeTracker) getTodaysProjects() map[string]time.Duration {
+	return computeProjects(tt.getTodaysActivities())
+}
+
+// computeStats totals work and break time over an already-built activity list
+func computeStats(activities []Activity) DayStats {
+	var workTime, breakTime, ignoredTime time.Duration
+	
+	for _, activity := range activities {
+		switch activity.Type {
+		case Work:
+			workTime += activity.counted()
+		case Break:
+			breakTime += activity.counted()
+		case Ignored:
+			ignoredTime += activity.counted()
+		}
+	}
+	
+	return DayStats{
+		WorkTime:    workTime,
+		BreakTime:   breakTime,
+		TotalTime:   workTime + breakTime,
+		IgnoredTime: ignoredTime,
+	}
+}
+
+// computeProjects sums work time per project over an already-built activity list
+func computeProjects(activities []Activity) map[string]time.Duration {
+	projects := make(map[string]time.Duration)
+	
+	for _, activity := range activities {
+		if activity.Type == Work {
+			projects[activity.Project] += activity.counted()
+		}
+	}
+	
+	return projects
+}
+
+// computeTags sums work time per tag, matching tags regardless of case
+func computeTags(activities []Activity) map[string]time.Duration {
+	tags := make(map[string]time.Duration)
+	names := make(map[string]string) // Lowercased tag -> first spelling seen
+	
+	for _, activity := range activities {
+		if activity.Type != Work {
+			continue
+		}
+		for _, tag := range activity.Tags {
+			key := strings.ToLower(tag)
+			if _, ok := names[key]; !ok {
+				names[key] = tag
+			}
+			tags[names[key]] += activity.counted()
+		}
+	}
+	
+	return tags
+}
+
+// overtimeSplit totals work per period (named by period(start)) and splits
+// each period's total into the part up to threshold and the overtime beyond it.
+// Periods still in progress count what was logged so far.
+func overtimeSplit(activities []Activity, threshold time.Duration, period func(time.Time) string) (regular, overtime time.Duration) {
+	work := make(map[string]time.Duration)
+	for _, activity := range activities {
+		if activity.Type == Work {
+			work[period(activity.Start)] += activity.counted()
+		}
+	}
+	for _, total := range work {
+		if total > threshold {
+			regular += threshold
+			overtime += total - threshold
+		} else {
+			regular += total
+		}
+	}
+	return regular, overtime
+}
+
+// dailyStats totals activities per day, returning the days in order
+func dailyStats(activities []Activity) ([]time.Time, map[string]DayStats) {
+	var days []time.Time
+	byDay := make(map[string][]Activity)
+	for _, activity := range activities {
+		key := dayKey(activity.Start)
+		if _, ok := byDay[key]; !ok {
+			days = append(days, startOfDay(activity.Start))
+		}
+		byDay[key] = append(byDay[key], activity)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	
+	stats := make(map[string]DayStats, len(byDay))
+	for key, dayActivities := range byDay {
+		stats[key] = computeStats(dayActivities)
+	}
+	return days, stats
+}
+
+// streak counts the consecutive days up to now whose work met
+// daily_target_hours. Today only counts once it's met, since it isn't over;
+// with skip_weekends, weekend days are passed over.
+func (tt *TimeTracker) streak(now time.Time) int {
+	target := hoursDuration(tt.config.DailyTargetHours)
+	if target <= 0 {
+		return 0
+	}
+	
+	count := 0
+	day := tt.todayStart(now)
+	if tt.workOn(day) < target {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; ; day = day.AddDate(0, 0, -1) {
+		if weekday := day.Weekday(); tt.config.SkipWeekends && (weekday == time.Saturday || weekday == time.Sunday) {
+			continue
+		}
+		if tt.workOn(day) < target {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// workOn is the work logged on the day beginning at day, remembered until
+// the entries change so the main view's streak doesn't rebuild every day on
+// each redraw
+func (tt *TimeTracker) workOn(day time.Time) time.Duration {
+	key := day.Format("2006-01-02 15")
+	if work, ok := tt.dayWork[key]; ok {
+		return work
+	}
+	if tt.dayWork == nil {
+		tt.dayWork = make(map[string]time.Duration)
+	}
+	work := computeStats(tt.activitiesOfDay(day)).WorkTime
+	tt.dayWork[key] = work
+	return work
+}
+
+// hoursDuration converts a configured number of hours to a Duration
+func hoursDuration(hours float64) time.Duration {
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// activityCounts are the texture figures shown under "Stats:" in reports
+type activityCounts struct {
+	Activities      int
+	Projects        int
+	ContextSwitches int           // Work activities whose project differs from the previous work activity
+	Average         time.Duration // Mean activity length
+}
+
+// computeCounts derives activityCounts; parallel tasks don't switch context
+func computeCounts(activities []Activity) activityCounts {
+	counts := activityCounts{
+		Activities: len(activities),
+		Projects:   len(computeProjects(activities)),
+	}
+	if len(activities) == 0 {
+		return counts
+	}
+	
+	var total time.Duration
+	var previous *Activity
+	for i := range activities {
+		activity := &activities[i]
+		total += activity.Duration
+		if activity.Type != Work || activity.Parallel {
+			continue
+		}
+		if previous != nil && previous.Project != activity.Project {
+			counts.ContextSwitches++
+		}
+		previous = activity
+	}
+	counts.Average = total / time.Duration(len(activities))
+	return counts
+}
+
+// showBillable reports whether any billable rule is configured
+func (c Config) showBillable() bool {
+	return len(c.BillableProjects) > 0 || c.AutoLunchMinutes > 0
+}
+
+// isBillable reports whether work on project counts as billable ("General"
+// matching work without a project, and a client all of its projects)
+func (c Config) isBillable(project string) bool {
+	if len(c.BillableProjects) == 0 {
+		return true
+	}
+	for _, billable := range c.BillableProjects {
+		if inProject(project, billable) {
+			return true
+		}
+	}
+	return false
+}
+
+// showEarnings reports whether any rate is set
+func (c Config) showEarnings() bool {
+	return c.HourlyRate > 0 || len(c.ProjectRates) > 0
+}
+
+// rate is what an hour of work on project bills: its project_rates entry, or
+// its client's, or else hourly_rate when the project is billable
+func (c Config) rate(project string) float64 {
+	for p, rate := range c.ProjectRates {
+		if strings.EqualFold(p, projectName(project)) {
+			return rate
+		}
+	}
+	for p, rate := range c.ProjectRates {
+		if inProject(project, p) {
+			return rate
+		}
+	}
+	if c.isBillable(project) {
+		return c.HourlyRate
+	}
+	return 0
+}
+
+// earnings is what the work in activities bills, in total and per project
+// ("General" for work without one)
+func (c Config) earnings(activities []Activity) (float64, map[string]float64) {
+	var total float64
+	projects := make(map[string]float64)
+	for project, d := range computeProjects(activities) {
+		amount := d.Hours() * c.rate(project)
+		if amount == 0 {
+			continue
+		}
+		if project == "" {
+			project = "General"
+		}
+		projects[project] += amount
+		total += amount
+	}
+	return total, projects
+}
+
+// earningsLines renders the "Billable:" total, followed by a line per
+// project when project_rates bills some of them differently
+func (c Config) earningsLines(activities []Activity) []string {
+	total, projects := c.earnings(activities)
+	lines := []string{"Billable: " + c.money(total)}
+	if len(c.ProjectRates) == 0 {
+		return lines
+	}
+	names := make([]string, 0, len(projects))
+	width := 0
+	for name := range projects {
+		names = append(names, name)
+		width = max(width, lipgloss.Width(name)+1)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %-*s  %s  (at %s/h)", width, name+":", c.money(projects[name]), c.money(c.rate(name))))
+	}
+	return lines
+}
+
+// money formats an amount in the configured currency
+func (c Config) money(amount float64) string {
+	return fmt.Sprintf("%s%.2f", c.Currency, amount)
+}
+
+// billableTime is the work on billable projects in a day's activities, less
+// AutoLunchMinutes when no break that long was logged and the day has
+// reached the afternoon
+func (c Config) billableTime(activities []Activity) time.Duration {
+	var billable time.Duration
+	tookLunch := false
+	reachedAfternoon := false
+	lunch := time.Duration(c.AutoLunchMinutes) * time.Minute
+	for _, activity := range activities {
+		switch activity.Type {
+		case Work:
+			if c.isBillable(activity.Project) {
+				billable += activity.counted()
+			}
+		case Break:
+			tookLunch = tookLunch || activity.Duration >= lunch
+		}
+		if activity.End.Hour() >= c.AfternoonStartHour {
+			reachedAfternoon = true
+		}
+	}
+	
+	if lunch > 0 && !tookLunch && reachedAfternoon {
+		billable -= lunch
+		if billable < 0 {
+			billable = 0
+		}
+	}
+	return billable
+}
+
+// dayPartLabels names the buckets returned by splitDayTotals
+var dayPartLabels = [3]string{"Morning", "Afternoon", "Evening"}
+
+// splitDayTotals spreads work time across morning, afternoon and evening by
+// clock time, so an activity spanning noon counts partly in each bucket
+func (tt *TimeTracker) splitDayTotals(activities []Activity) [3]time.Duration {
+	var totals [3]time.Duration
+	for _, activity := range activities {
+		if activity.Type != Work {
+			continue
+		}
+		for day := startOfDay(activity.Start); day.Before(activity.End); day = day.AddDate(0, 0, 1) {
+			afternoon := day.Add(time.Duration(tt.config.AfternoonStartHour) * time.Hour)
+			evening := day.Add(time.Duration(tt.config.EveningStartHour) * time.Hour)
+			bounds := [4]time.Time{day, afternoon, evening, day.AddDate(0, 0, 1)}
+			for i := range totals {
+				totals[i] += overlap(activity.Start, activity.End, bounds[i], bounds[i+1])
+			}
+		}
+	}
+	return totals
+}
+
+// hourlyTotals spreads work time across the 24 clock hours, so an activity
+// from 9:40 to 11:10 counts 20 minutes at 9, an hour at 10 and 10 minutes at 11.
+// Each hour gets its share of the counted time, so rounding and overlap with a
+// parallel activity shrink the bars the same way they shrink the totals.
+func hourlyTotals(activities []Activity) [24]time.Duration {
+	var totals [24]time.Duration
+	for _, activity := range activities {
+		wall := activity.End.Sub(activity.Start)
+		if activity.Type != Work || wall <= 0 {
+			continue
+		}
+		scale := float64(activity.counted()) / float64(wall)
+		for t := activity.Start; t.Before(activity.End); {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			if next.After(activity.End) {
+				next = activity.End
+			}
+			totals[t.Hour()] += time.Duration(float64(next.Sub(t)) * scale)
+			t = next
+		}
+	}
+	return totals
+}
+
+// heatmapLines renders hourly totals as one bar per hour, scaled to the
+// busiest hour, from the first hour with work to the last
+func heatmapLines(show displayFormats, totals [24]time.Duration, width int) []string {
+	first, last := -1, -1
+	var busiest time.Duration
+	for hour, d := range totals {
+		if d <= 0 {
+			continue
+		}
+		if first < 0 {
+			first = hour
+		}
+		last = hour
+		busiest = max(busiest, d)
+	}
+	if first < 0 {
+		return []string{"  No work in this period."}
+	}
+	
+	var lines []string
+	for hour := first; hour <= last; hour++ {
+		lines = append(lines, fmt.Sprintf("  %02d:00  %s  %s", hour,
+			progressBar(width, float64(totals[hour])/float64(busiest)), show.duration(totals[hour])))
+	}
+	return lines
+}
+
+// printHeatmap prints the work by hour of day of a report
+func printHeatmap(show displayFormats, title string, activities []Activity) {
+	fmt.Println(paint(subtitleStyle, title))
+	fmt.Println(paint(subtitleStyle, strings.Repeat("=", utf8.RuneCountInString(title))))
+	fmt.Println()
+	fmt.Println(paint(subtitleStyle, "Work by Hour of Day:"))
+	for _, line := range heatmapLines(show, hourlyTotals(activities), 30) {
+		fmt.Println(paint(workStyle, line))
+	}
+}
+
+func (tt *TimeTracker) generateSummary(activities []Activity) string {
+	show := tt.config.display()
+	stats := computeStats(activities)
+	
+	var summary strings.Builder
+	
+	// Time summary
+	summary.WriteString(subtitleStyle.Render("Time Summary:") + "\n\n")
+	summary.WriteString(workStyle.Render(fmt.Sprintf("  Work:  %s", show.duration(stats.WorkTime))) + "\n")
+	summary.WriteString(breakStyle.Render(fmt.Sprintf("  Break: %s", show.duration(stats.BreakTime))) + "\n")
+	summary.WriteString(subtitleStyle.Render(fmt.Sprintf("  Total: %s", show.duration(stats.TotalTime))) + "\n")
+	if split := workBreakSplit(stats, 20); split != "" {
+		summary.WriteString(split + "\n")
+	}
+	if tt.config.IncludeIgnored {
+		summary.WriteString(ignoredStyle.Render(fmt.Sprintf("  Elapsed: %s (with %s ignored)",
+			show.duration(stats.Elapsed()), show.duration(stats.IgnoredTime))) + "\n")
+	}
+	summary.WriteString("\n")
+	
+	// Work split across the day
+	if tt.config.SplitDay {
+		split := tt.splitDayTotals(activities)
+		summary.WriteString(subtitleStyle.Render("Work by Time of Day:") + "\n\n")
+		for i, label := range dayPartLabels {
+			summary.WriteString(workStyle.Render(fmt.Sprintf("  %-10s %s", label+":", show.duration(split[i]))) + "\n")
+		}
+		summary.WriteString("\n")
+	}
+	
+	// Totals per day, when the activities span several
+	if days, stats := dailyStats(activities); len(days) > 1 {
+		summary.WriteString(subtitleStyle.Render("Days:") + "\n\n")
+		for _, day := range days {
+			summary.WriteString(workStyle.Render(fmt.Sprintf("  %s  Work: %s  Break: %s", day.Format("Mon 01-02"),
+				show.duration(stats[dayKey(day)].WorkTime), show.duration(stats[dayKey(day)].BreakTime))) + "\n")
+		}
+		summary.WriteString("\n")
+	}
+	
+	// Project breakdown, counting overlapping parallel work like the CLI report
+	projects := computeProjects(activities)
+	if len(projects) > 0 {
+		summary.WriteString(subtitleStyle.Render("Projects:") + "\n\n")
+		lines := formatProjectLines(show, sortedProjects(projects), tt.config.ProjectDepth > 1)
+		for i, line := range lines {
+			style := workStyle
+			if i == len(lines)-1 {
+				style = subtitleStyle
+			}
+			summary.WriteString(style.Render(line) + "\n")
+		}
+	}
+	
+	if tags := computeTags(activities); len(tags) > 0 {
+		summary.WriteString("\n" + subtitleStyle.Render("Tags:") + "\n\n")
+		for _, line := range formatTagLines(show, sortedProjects(tags)) {
+			summary.WriteString(workStyle.Render(line) + "\n")
+		}
+	}
+	
+	if tt.config.showEarnings() && len(activities) > 0 {
+		summary.WriteString("\n")
+		for i, line := range tt.config.earningsLines(activities) {
+			style := workStyle
+			if i == 0 {
+				style = subtitleStyle
+			}
+			summary.WriteString(style.Render(line) + "\n")
+		}
+	}
+	
+	return summary.String()
+}
+
+// Helper functions
+
+// metaPrefix marks metadata tokens in task input ("@ticket=PROJ-42") so an
+// "=" in ordinary task text is never mistaken for metadata
+const metaPrefix = "@"
+
+// splitMeta separates @key=value tokens from the rest of the task input
+func splitMeta(input string) (string, map[string]string) {
+	var meta map[string]string
+	var words []string
+	for _, word := range strings.Fields(input) {
+		key, value, ok := strings.Cut(strings.TrimPrefix(word, metaPrefix), "=")
+		if !strings.HasPrefix(word, metaPrefix) || !ok || key == "" {
+			words = append(words, word)
+			continue
+		}
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		meta[key] = value
+	}
+	if meta == nil {
+		return input, nil
+	}
+	return strings.Join(words, " "), meta
+}
+
+// hasTaskName reports whether name still names a task once its +tags and
+// type markers are taken out, so "@ticket=42" or "+urgent" alone isn't one
+func (p nameParser) hasTaskName(name string) bool {
+	return p.parseName(name).Name != ""
+}
+
+// tagPrefix marks free-form tags in task input ("+urgent")
+const tagPrefix = "+"
+
+// splitTags separates +tag tokens from the rest of a task name. A tag starts
+// with a letter, so "+1" in "Estimate +1 day" stays part of the name.
+func splitTags(input string) (string, []string) {
+	var tags []string
+	var words []string
+	for _, word := range strings.Fields(input) {
+		tag := strings.TrimPrefix(word, tagPrefix)
+		if !strings.HasPrefix(word, tagPrefix) || tag == "" || !unicode.IsLetter([]rune(tag)[0]) {
+			words = append(words, word)
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if tags == nil {
+		return input, nil
+	}
+	return strings.Join(words, " "), tags
+}
+
+// mergeTags appends the tags in more that aren't in tags yet, ignoring case
+func mergeTags(tags, more []string) []string {
+	tags = append([]string(nil), tags...) // The activity's own tags may be shared
+	for _, tag := range more {
+		found := false
+		for _, t := range tags {
+			found = found || strings.EqualFold(t, tag)
+		}
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// nameWithTags is an entry's name with its tags written back as +tag tokens,
+// for editing
+func nameWithTags(entry Entry) string {
+	name := entry.Name
+	for _, tag := range entry.Tags {
+		name += " " + tagPrefix + tag
+	}
+	return name
+}
+
+// parseMetaPairs parses comma-separated key=value pairs as given to -meta and -where
+func parseMetaPairs(s string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key = strings.TrimPrefix(strings.TrimSpace(key), metaPrefix)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not key=value", strings.TrimSpace(pair))
+		}
+		meta[key] = strings.TrimSpace(value)
+	}
+	return meta, nil
+}
+
+// matchesMeta reports whether meta holds every pair in want
+func matchesMeta(meta, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := meta[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatMeta renders metadata as "@key=value" tokens sorted by key
+func formatMeta(meta map[string]string) string {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tokens := make([]string, len(keys))
+	for i, key := range keys {
+		tokens[i] = metaPrefix + key + "=" + meta[key]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// ParsedName holds everything encoded in an entry's name
+type ParsedName struct {
+	Name    string // Name with type markers removed
+	Type    ActivityType
+	Project string
+	Task    string
+	Tags    []string // #tags and +tags in the name, without the prefix
+}
+
+// nameParser reads task names with the type markers from break_marker and
+// ignored_marker, and the project levels from project_depth
+type nameParser struct {
+	breakMarker   string
+	ignoredMarker string
+	projectDepth  int // Colon-separated levels that form the project, at least 1
+}
+
+// parser reads names the way this config writes them
+func (c Config) parser() nameParser {
+	return nameParser{breakMarker: c.BreakMarker, ignoredMarker: c.IgnoredMarker, projectDepth: max(c.ProjectDepth, 1)}
+}
+
+
+// parseName splits an entry name into its type, project and task. Type markers
+// are matched longest first so "***" is never mistaken for "**", and a colon
+// only separates the project when it isn't part of a clock time like "3:00".
+func (p nameParser) parseName(name string) ParsedName {
+	name = strings.TrimSpace(name)
+	parsed := ParsedName{Type: Work}
+	
+	// Determine activity type from a marker at the end, or else the start
+	markers := []struct {
+		marker string
+		t      ActivityType
+	}{{p.ignoredMarker, Ignored}, {p.breakMarker, Break}}
+	if len(p.breakMarker) > len(p.ignoredMarker) {
+		markers[0], markers[1] = markers[1], markers[0]
+	}
+	for _, m := range markers {
+		if m.marker != "" && strings.HasSuffix(name, m.marker) {
+			parsed.Type = m.t
+			name = strings.TrimSpace(strings.TrimSuffix(name, m.marker))
+			break
+		}
+		if m.marker != "" && strings.HasPrefix(name, m.marker) {
+			parsed.Type = m.t
+			name = strings.TrimSpace(strings.TrimPrefix(name, m.marker))
+			break
+		}
+	}
+	// +tags aren't part of the name shown
+	name, plusTags := splitTags(name)
+	parsed.Name = name
+	parsed.Task = name
+	
+	// Parse project:task format, with up to p.projectDepth levels of project
+	// ("Client: Project: Task"). The last part is always left as the task.
+	var levels []string
+	rest := name
+	for len(levels) < max(p.projectDepth, 1) {
+		i := projectSeparator(rest)
+		if i < 0 {
+			break
+		}
+		level := strings.TrimSpace(rest[:i])
+		if level == "" || strings.TrimSpace(rest[i+1:]) == "" {
+			break
+		}
+		levels = append(levels, level)
+		rest = rest[i+1:]
+	}
+	if len(levels) > 0 {
+		parsed.Project = strings.Join(levels, ": ")
+		parsed.Task = strings.TrimSpace(rest)
+	}
+	
+	parsed.Tags = mergeTags(parseTags(name), plusTags)
+	return parsed
+}
+
+// parseTags returns the #tags in a name. A tag starts with a letter, so
+// "#123" in "Bug fix #123" stays an issue number.
+func parseTags(name string) []string {
+	var tags []string
+	for _, word := range strings.Fields(name) {
+		tag := strings.TrimRight(strings.TrimPrefix(word, "#"), ".,;:!?")
+		if !strings.HasPrefix(word, "#") || tag == "" || !unicode.IsLetter([]rune(tag)[0]) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// projectSeparator returns the index of the first colon that separates a
+// project from its task, skipping colons between digits, or -1 if none
+func projectSeparator(name string) int {
+	for i := 0; i < len(name); i++ {
+		if name[i] != ':' {
+			continue
+		}
+		if i > 0 && i+1 < len(name) && isDigit(name[i-1]) && isDigit(name[i+1]) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p nameParser) parseActivity(entry Entry, start, end time.Time, isCurrent bool) Activity {
+	parsed := p.parseName(entry.Name)
+	if t, ok := parseActivityType(entry.Type); ok {
+		parsed.Type = t
+	}
+	
+	return Activity{
+		Name:      parsed.Name,
+		Start:     start,
+		End:       end,
+		Duration:  end.Sub(start),
+		Type:      parsed.Type,
+		Project:   parsed.Project,
+		Task:      parsed.Task,
+		Comment:   entry.Comment,
+		IsCurrent: isCurrent,
+		Meta:      entry.Meta,
+		Tags:      mergeTags(parsed.Tags, entry.Tags),
+	}
+}
+
+// activity builds an activity with parseActivity, then gives it the project
+// TagProjectMap assigns to one of its tags when the name has no project.
+// An explicit "Project:" prefix always wins.
+func (tt *TimeTracker) activity(entry Entry, start, end time.Time) Activity {
+	activity := tt.config.parser().parseActivity(entry, start, end, false)
+	if activity.Project != "" || len(tt.config.TagProjectMap) == 0 {
+		return activity
+	}
+	for _, tag := range activity.Tags {
+		for key, project := range tt.config.TagProjectMap {
+			if strings.EqualFold(strings.TrimLeft(key, "#"+tagPrefix), tag) {
+				activity.Project = canonicalProject(project)
+				return activity
+			}
+		}
+	}
+	return activity
+}
+
+// projectTotal is one row of a project breakdown
+type projectTotal struct {
+	Name     string
+	Duration time.Duration
+	Sub      bool // A nested project, already counted in the client row above it
+}
+
+// sortedProjects orders project totals by duration, longest first and then
+// by name so renders never reorder, labelling the unnamed project "General"
+func sortedProjects(projects map[string]time.Duration) []projectTotal {
+	totals := make([]projectTotal, 0, len(projects))
+	for name, duration := range projects {
+		if name == "" {
+			name = "General"
+		}
+		totals = append(totals, projectTotal{Name: name, Duration: duration})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Duration != totals[j].Duration {
+			return totals[i].Duration > totals[j].Duration
+		}
+		return totals[i].Name < totals[j].Name
+	})
+	return totals
+}
+
+// formatProjectLines renders project totals as aligned "Name: duration share"
+// rows followed by a Total row, nested under their clients when nested
+func formatProjectLines(show displayFormats, projects []projectTotal, nested bool) []string {
+	if nested {
+		projects = nestProjects(projects)
+	}
+	return formatTotalLines(show, projects, true)
+}
+
+// nestProjects groups "Client: Project" totals under a row per top-level
+// client, longest first, with the projects indented beneath it
+func nestProjects(projects []projectTotal) []projectTotal {
+	var clients []projectTotal
+	nested := make(map[string][]projectTotal)
+	for _, p := range projects {
+		client, sub := p.Name, ""
+		if i := projectSeparator(p.Name); i >= 0 {
+			client, sub = strings.TrimSpace(p.Name[:i]), strings.TrimSpace(p.Name[i+1:])
+		}
+		if _, ok := nested[client]; !ok {
+			clients = append(clients, projectTotal{Name: client})
+			nested[client] = nil
+		}
+		for i := range clients {
+			if clients[i].Name == client {
+				clients[i].Duration += p.Duration
+			}
+		}
+		if sub != "" {
+			nested[client] = append(nested[client], projectTotal{Name: "  " + sub, Duration: p.Duration, Sub: true})
+		}
+	}
+	sort.SliceStable(clients, func(i, j int) bool {
+		return clients[i].Duration > clients[j].Duration
+	})
+	
+	var rows []projectTotal
+	for _, client := range clients {
+		rows = append(rows, client)
+		rows = append(rows, nested[client.Name]...)
+	}
+	return rows
+}
+
+// formatTagLines renders tag totals like formatProjectLines, but without a
+// Total row or shares, since an activity with several tags counts toward each
+func formatTagLines(show displayFormats, tags []projectTotal) []string {
+	return formatTotalLines(show, tags, false)
+}
+
+func formatTotalLines(show displayFormats, projects []projectTotal, withTotal bool) []string {
+	var total time.Duration
+	nameWidth := len("Total:")
+	durWidth := 0
+	for _, p := range projects {
+		if !p.Sub {
+			total += p.Duration
+		}
+		nameWidth = max(nameWidth, lipgloss.Width(p.Name)+1)
+		durWidth = max(durWidth, len(show.duration(p.Duration)))
+	}
+	durWidth = max(durWidth, len(show.duration(total)))
+	
+	row := func(name string, d time.Duration) string {
+		label := name + ":"
+		pad := strings.Repeat(" ", nameWidth-lipgloss.Width(label))
+		return fmt.Sprintf("  %s%s  %*s", label, pad, durWidth, show.duration(d))
+	}
+	
+	lines := make([]string, 0, len(projects)+1)
+	for _, p := range projects {
+		line := row(p.Name, p.Duration)
+		if withTotal && total > 0 {
+			line += fmt.Sprintf("  %3.0f%%", float64(p.Duration)/float64(total)*100)
+		}
+		lines = append(lines, line)
+	}
+	if !withTotal {
+		return lines
+	}
+	return append(lines, row("Total", total))
+}
+
+// weeklyGoalStyle picks the colour of the weekly goal readout: green once the
+// goal is met, a warning tint when few days remain and much is left to do,
+// and the regular work colour otherwise. daysLeft excludes today.
+func weeklyGoalStyle(progress float64, daysLeft int) lipgloss.Style {
+	switch {
+	case progress >= 1:
+		return successStyle
+	case daysLeft <= 1 && progress < 0.75:
+		return warningStyle
+	default:
+		return workStyle
+	}
+}
+
+// typeStyle is the colour used for activities of type t
+func typeStyle(t ActivityType) lipgloss.Style {
+	switch t {
+	case Break:
+		return breakStyle
+	case Ignored:
+		return ignoredStyle
+	default:
+		return workStyle
+	}
+}
+
+// activitySpan returns the earliest start and latest end of activities
+func activitySpan(activities []Activity) (start, end time.Time) {
+	start, end = activities[0].Start, activities[0].End
+	for _, activity := range activities {
+		if activity.Start.Before(start) {
+			start = activity.Start
+		}
+		if activity.End.After(end) {
+			end = activity.End
+		}
+	}
+	return start, end
+}
+
+// timelineCells maps each of width cells spanning the first start to the last
+// end of activities to the index of the activity covering its midpoint: -1
+// for a gap, -2 where activities overlap
+func timelineCells(activities []Activity, width int) []int {
+	if len(activities) == 0 || width <= 0 {
+		return nil
+	}
+	cells := make([]int, width)
+	start, end := activitySpan(activities)
+	span := end.Sub(start)
+	
+	for i := range cells {
+		at := start.Add(time.Duration((float64(i) + 0.5) / float64(width) * float64(span)))
+		cells[i] = -1
+		for j, activity := range activities {
+			if at.Before(activity.Start) || !at.Before(activity.End) {
+				continue
+			}
+			if cells[i] >= 0 {
+				cells[i] = -2
+				break
+			}
+			cells[i] = j
+		}
+	}
+	return cells
+}
+
+// renderTimeline draws activities as a bar width cells wide, coloured by
+// type, with gaps dotted and overlaps shaded, labels underneath where an
+// activity is wide enough, and the first start and last end below that
+func renderTimeline(show displayFormats, activities []Activity, width int) string {
+	cells := timelineCells(activities, width)
+	if len(cells) == 0 {
+		return ""
+	}
+	
+	// Group runs of cells showing the same thing
+	var bar, labels strings.Builder
+	for i := 0; i < len(cells); {
+		j := i
+		for j < len(cells) && cells[j] == cells[i] {
+			j++
+		}
+		run := j - i
+		switch idx := cells[i]; {
+		case idx == -1:
+			bar.WriteString(helpStyle.Render(strings.Repeat("·", run)))
+			labels.WriteString(strings.Repeat(" ", run))
+		case idx == -2:
+			bar.WriteString(warningStyle.Render(strings.Repeat("▒", run)))
+			labels.WriteString(strings.Repeat(" ", run))
+		default:
+			// Alternate the fill so neighbours of the same type stay distinct
+			fill := "█"
+			if idx%2 == 1 {
+				fill = "▓"
+			}
+			activity := activities[idx]
+			bar.WriteString(typeStyle(activity.Type).Render(strings.Repeat(fill, run)))
+			
+			label := activity.Task
+			if activity.Project != "" {
+				label = activity.Project
+			}
+			labelRunes := []rune(label)
+			if len(labelRunes) >= run {
+				labelRunes = labelRunes[:max(run-1, 0)]
+			}
+			labels.WriteString(typeStyle(activity.Type).Render(string(labelRunes)))
+			labels.WriteString(strings.Repeat(" ", run-len(labelRunes)))
+		}
+		i = j
+	}
+	
+	first, last := activitySpan(activities)
+	startLabel, endLabel := show.clock(first), show.clock(last)
+	axis := startLabel + strings.Repeat(" ", max(width-len(startLabel)-len(endLabel), 1)) + endLabel
+	
+	return bar.String() + "\n" + labels.String() + "\n" + helpStyle.Render(axis)
+}
+
+// targetProgress reads work against a target, e.g. "6h30 / 8h00 (81%)"
+func targetProgress(show displayFormats, work, target time.Duration) string {
+	return fmt.Sprintf("%s / %s (%d%%)", show.duration(work), show.duration(target),
+		int(math.Round(float64(work)/float64(target)*100)))
+}
+
+// progressBar renders a fixed-width bar filled to fraction (clamped to 0..1)
+func progressBar(width int, fraction float64) string {
+	fraction = math.Max(0, math.Min(1, fraction))
+	filled := int(math.Round(fraction * float64(width)))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// workBreakSplit renders the shares of work and break in the total as
+// "Work 85% / Break 15%" with a two-segment bar, or "" before anything counts
+func workBreakSplit(stats DayStats, width int) string {
+	if stats.TotalTime <= 0 {
+		return ""
+	}
+	share := float64(stats.WorkTime) / float64(stats.TotalTime)
+	percent := int(math.Round(share * 100))
+	filled := int(math.Round(share * float64(width)))
+	bar := workStyle.Render(strings.Repeat("█", filled)) + breakStyle.Render(strings.Repeat("█", width-filled))
+	return "  " + infoStyle.Render(fmt.Sprintf("Work %d%% / Break %d%%", percent, 100-percent)) + "  " + bar
+}
+
+// indentComment prefixes every line of a (possibly multi-line) comment
+func indentComment(comment, prefix string) string {
+	lines := strings.Split(strings.TrimRight(comment, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// overlap returns how much of [aStart, aEnd) falls inside [bStart, bEnd)
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start := aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end := aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// dayKey identifies a calendar day in day-keyed storage
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// startOfDay returns local midnight of the day containing t
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight of the first day of the week containing t
+func startOfWeek(t time.Time, weekStart time.Weekday) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// reportTypes returns the activity types shown in the report table
+func (c Config) reportTypes() map[ActivityType]bool {
+	types := make(map[ActivityType]bool)
+	for _, name := range c.ReportShowTypes {
+		if t, ok := parseActivityType(name); ok {
+			types[t] = true
+		}
+	}
+	if len(types) == 0 {
+		return allTypeFilters[0]
+	}
+	return types
+}
+
+// allTypeFilters are the report table filters cycled through in the TUI
+var allTypeFilters = []map[ActivityType]bool{
+	{Work: true, Break: true, Ignored: true},
+	{Work: true, Break: true},
+	{Work: true},
+}
+
+// weekStartDay parses the configured week start, defaulting to Monday
+func (c Config) weekStartDay() time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(c.WeekStart, d.String()) {
+			return d
+		}
+	}
+	return time.Monday
+}
+
+// maxRangeDays bounds -last Nd and Nw, since a report builds every day in its
+// range: ten years is more history than anyone logs
+const maxRangeDays = 3660
+
+// parseRangeSpec resolves a relative report range into concrete [start, end)
+// bounds. The accepted grammar is:
+//
+//	-last Nd | Nw | week | month
+//	-this week | month | year
+//
+// "Nd" and "Nw" count back from today inclusive, at most maxRangeDays;
+// "week", "month" and "year" are calendar periods.
+func parseRangeSpec(kind, spec string, now time.Time, weekStart time.Weekday) (start, end time.Time, label string, err error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	today := startOfDay(now)
+	
+	switch kind {
+	case "this":
+		switch spec {
+		case "week":
+			start = startOfWeek(now, weekStart)
+			return start, start.AddDate(0, 0, 7), "this week", nil
+		case "month":
+			start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+			return start, start.AddDate(0, 1, 0), "this month", nil
+		case "year":
+			start = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+			return start, start.AddDate(1, 0, 0), "this year", nil
+		}
+		return start, end, "", fmt.Errorf("invalid range 'this %s' (use week, month or year)", spec)
+		
+	case "last":
+		switch spec {
+		case "week":
+			end = startOfWeek(now, weekStart)
+			return end.AddDate(0, 0, -7), end, "last week", nil
+		case "month":
+			end = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+			return end.AddDate(0, -1, 0), end, "last month", nil
+		}
+		
+		if len(spec) < 2 {
+			return start, end, "", fmt.Errorf("invalid range 'last %s' (use Nd, Nw, week or month)", spec)
+		}
+		unit := spec[len(spec)-1]
+		n, convErr := strconv.Atoi(spec[:len(spec)-1])
+		if convErr != nil || n <= 0 {
+			return start, end, "", fmt.Errorf("invalid range 'last %s' (use Nd, Nw, week or month)", spec)
+		}
+		
+		days := n
+		unitName := "day"
+		switch unit {
+		case 'd':
+		case 'w':
+			days = n * 7
+			unitName = "week"
+		default:
+			return start, end, "", fmt.Errorf("invalid range unit in 'last %s' (use d or w)", spec)
+		}
+		if n > maxRangeDays || days > maxRangeDays {
+			return start, end, "", fmt.Errorf("range 'last %s' is too long (at most %d days)", spec, maxRangeDays)
+		}
+		if n != 1 {
+			unitName += "s"
+		}
+		end = today.AddDate(0, 0, 1)
+		return end.AddDate(0, 0, -days), end, fmt.Sprintf("last %d %s", n, unitName), nil
+	}
+	
+	return start, end, "", fmt.Errorf("unknown range kind %q", kind)
+}
+
+// bounds resolves the sprint into [start, end) days
+func (s Sprint) bounds() (start, end time.Time, err error) {
+	start, err = time.ParseInLocation("2006-01-02", s.Start, time.Local)
+	if err != nil {
+		return start, end, fmt.Errorf("sprint %q has an invalid start %q (use YYYY-MM-DD)", s.Name, s.Start)
+	}
+	last, err := time.ParseInLocation("2006-01-02", s.End, time.Local)
+	if err != nil {
+		return start, end, fmt.Errorf("sprint %q has an invalid end %q (use YYYY-MM-DD)", s.Name, s.End)
+	}
+	if last.Before(start) {
+		return start, end, fmt.Errorf("sprint %q ends before it starts", s.Name)
+	}
+	return start, last.AddDate(0, 0, 1), nil
+}
+
+// findSprint looks a sprint up by name, case-insensitively, or picks the one
+// containing now for "current". The sprints are checked first: every one
+// must have valid dates and no two may overlap.
+func (c Config) findSprint(name string, now time.Time) (sprint Sprint, start, end time.Time, err error) {
+	starts := make([]time.Time, len(c.Sprints))
+	ends := make([]time.Time, len(c.Sprints))
+	for i, s := range c.Sprints {
+		if starts[i], ends[i], err = s.bounds(); err != nil {
+			return sprint, start, end, err
+		}
+		for j := 0; j < i; j++ {
+			if starts[i].Before(ends[j]) && starts[j].Before(ends[i]) {
+				return sprint, start, end, fmt.Errorf("sprints %q and %q overlap", c.Sprints[j].Name, s.Name)
+			}
+		}
+	}
+	
+	for i, s := range c.Sprints {
+		if strings.EqualFold(name, "current") && !now.Before(starts[i]) && now.Before(ends[i]) {
+			return s, starts[i], ends[i], nil
+		}
+		if strings.EqualFold(name, s.Name) {
+			return s, starts[i], ends[i], nil
+		}
+	}
+	if strings.EqualFold(name, "current") {
+		return sprint, start, end, fmt.Errorf("no sprint includes today")
+	}
+	return sprint, start, end, fmt.Errorf("no sprint named %q", name)
+}
+
+// parseFromTo resolves -from and -to days, both included, into [start, end)
+// bounds; to defaults to today
+func parseFromTo(from, to string, now time.Time) (start, end time.Time, err error) {
+	if from == "" {
+		return start, end, errors.New("-to needs -from")
+	}
+	start, err = time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid -from %q (use YYYY-MM-DD)", from)
+	}
+	last := startOfDay(now)
+	if to != "" {
+		if last, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return start, end, fmt.Errorf("invalid -to %q (use YYYY-MM-DD)", to)
+		}
+	}
+	if last.Before(start) {
+		return start, end, errors.New("-to is before -from")
+	}
+	return start, last.AddDate(0, 0, 1), nil
+}
+
+// writeHours writes the work in [start, end) as decimal hours and nothing
+// else, for scripts
+func writeHours(w io.Writer, tracker *TimeTracker, start, end time.Time, opts reportOptions) {
+	stats := computeStats(opts.apply(tracker.activitiesIn(start, end)))
+	fmt.Fprintln(w, decimalHours(stats.WorkTime))
+}
+
+// decimalHours writes a duration as hours with up to two decimals: "37.5"
+func decimalHours(d time.Duration) string {
+	return strconv.FormatFloat(math.Round(d.Hours()*100)/100, 'f', -1, 64)
+}
+
+// humanizeSince describes how long ago something happened: "just now",
+// "3m ago", "1h12 ago", "yesterday" or "4 days ago"
+func humanizeSince(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02d ago", int(d.Hours()), int(d.Minutes())%60)
+	case d < 48*time.Hour:
+		return "yesterday"
+	default:
+		return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+	}
+}
+
+// displayFormats shows clock times and durations the way time_format and
+// duration_format ask
+type displayFormats struct {
+	clockLayout   string
+	durationStyle string
+}
+
+// display is how this config shows clock times and durations
+func (c Config) display() displayFormats {
+	return displayFormats{clockLayout: clockLayoutFor(c.TimeFormat), durationStyle: c.DurationFormat}
+}
+
+// clockLayoutFor turns time_format into a time layout, "24h" when empty
+func clockLayoutFor(format string) string {
+	switch strings.ToLower(format) {
+	case "", "24h":
+		return "15:04"
+	case "12h":
+		return "3:04PM"
+	}
+	return format
+}
+
+// clock shows the time of day of t
+func (f displayFormats) clock(t time.Time) string {
+	return t.Format(f.clockLayout)
+}
+
+// clockSpan shows "start-end", padded on the left to the widest span so
+// 12-hour times line up in columns
+func (f displayFormats) clockSpan(start, end time.Time) string {
+	width := 2*len(time.Date(2000, 1, 1, 22, 0, 0, 0, time.UTC).Format(f.clockLayout)) + 1
+	return fmt.Sprintf("%*s", width, f.clock(start)+"-"+f.clock(end))
+}
+
+// duration shows d in the configured style. Negative durations, which only
+// come from entries out of order, get a leading "-", and durations under a
+// minute show their seconds ("40s") rather than "0h00". Totals stay in hours
+// however long; see span for a single stretch of time.
+func (f displayFormats) duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + f.duration(-d)
+	}
+	if f.durationStyle == "decimal" {
+		return fmt.Sprintf("%.2fh", d.Hours())
+	}
+	if d > 0 && d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if f.durationStyle == "colon" {
+		return fmt.Sprintf("%d:%02d", hours, minutes)
+	}
+	return fmt.Sprintf("%dh%02d", hours, minutes)
+}
+
+// span shows a single stretch of time, such as how long ago the last entry
+// was, like duration except that the default style shows a day or more as
+// days ("1d 2h00")
+func (f displayFormats) span(d time.Duration) string {
+	if d < 0 {
+		return "-" + f.span(-d)
+	}
+	if f.durationStyle == "decimal" || f.durationStyle == "colon" || d < 24*time.Hour {
+		return f.duration(d)
+	}
+	hours := int(d.Hours())
+	return fmt.Sprintf("%dd %dh%02d", hours/24, hours%24, int(d.Minutes())%60)
+}
+
+// printCLIHelp prints the command-line help, with examples written in the
+// configured type markers
+func printCLIHelp(names nameParser) {
+	fmt.Println("tt - Time Tracker")
+	fmt.Println()
+	fmt.Println("USAGE:")
+	fmt.Println("  tt                    Start TUI interface")
+	fmt.Println("  tt [command]          Run command and exit")
+	fmt.Println()
+	fmt.Println("COMMANDS:")
+	fmt.Println("  -s                    Start your day")
+	fmt.Println("  -e                    End your day (a task logged later counts from the Stop;")
+	fmt.Println("                        run -s when you resume to start a new stretch)")
+	fmt.Println("  -a \"task name\"        Add completed task (@alias words expand from shortcuts)")
+	fmt.Println("  -c \"comment\"          Add comment (use with -a)")
+	fmt.Println("  -t HH:MM              When the task finished, if not now (use with -a; also RFC3339)")
+	fmt.Println("  -ago 30m              How long ago the task finished (use with -a instead of -t)")
+	fmt.Println("  -type <type>          Task type for -a: work, break or ignored")
+	fmt.Println("  -q N                  Log quick task N from quick_tasks")
+	fmt.Println("  -meta k=v,k=v         Metadata for -a (or write @k=v in the task name)")
+	fmt.Println("  -r                    Show today's report")
+	fmt.Println("  -r week | month       Report over this week or month")
+	fmt.Println("  -r json               Print the report as JSON (with any range)")
+	fmt.Println("  -r heatmap            Chart work by hour of day (with any range)")
+	fmt.Println("  -last <range>         Report over Nd, Nw, week or month (e.g. 7d)")
+	fmt.Println("  -this <period>        Report over this week, month or year")
+	fmt.Println("  -sprint <name>        Report over a sprint from sprints (or \"current\")")
+	fmt.Println("  -from D [-to D]       Report over the days from D to D (YYYY-MM-DD, -to defaults to today)")
+	fmt.Println("  -pace                 When today's work reaches daily_target_hours at the current rate")
+	fmt.Println("  -hours                Print only the work hours of the day or range, e.g. 37.5")
+	fmt.Println("  -open \"task\"          Start a parallel task (needs parallel_mode)")
+	fmt.Println("  -close \"task\"         Stop a running parallel task")
+	fmt.Println("  -edit                 Open the data file in editor (or $EDITOR), then reload it")
+	fmt.Println("  -in \"task\"            Clock in to a task (clocks out of the current one)")
+	fmt.Println("  -out                  Clock out of the task clocked in with -in")
+	fmt.Println("  -project-stats \"p\"    Lifetime totals for one project")
+	fmt.Println("  -find \"text\"         Activities whose name or comment contains the text")
+	fmt.Println("  -anonymize            Use pseudonyms for names in reports (for sharing)")
+	fmt.Println("  -where k=v,k=v        Only report activities with this metadata")
+	fmt.Println("  -project \"p\"          Only report or export one project (\"General\" for none)")
+	fmt.Println("  -only-work            Leave breaks and ignored activities out of reports")
+	fmt.Println("  -billable             Only report work on billable_projects")
+	fmt.Println("  -x                    Extend last task to now")
+	fmt.Println("  -u                    Remove the most recent entry (undo)")
+	fmt.Println("  -note \"text\"          Append to the comment of the last task")
+	fmt.Println("  -force, -f            Extend even if the last entry was just logged, add a -t")
+	fmt.Println("                        task before the last entry, or start a started day again")
+	fmt.Println("  -i                    Preview the resulting duration and confirm (use with -x)")
+	fmt.Println("                        With -a, offer to split a long gap off as idle time")
+	fmt.Println("  -normalize            Clean up the data file (sort, trim, dedupe)")
+	fmt.Println("    -dry-run            Only show what would change")
+	fmt.Println("    -no-sort, -no-trim, -no-dedupe  Skip a step")
+	fmt.Println("    -collapse           Also merge same-name entries under a minute apart (not with -no-sort)")
+	fmt.Println("  -import-calendar f    Import meetings from an .ics file")
+	fmt.Println("  -import-summary f     Import daily totals from a date,hours,project CSV file")
+	fmt.Println("  -import file.csv      Import entries from a timestamp,name,comment CSV file")
+	fmt.Println("  -git                  Log commits made here since the last entry as tasks")
+	fmt.Println("  -export ics           Print the day's activities as calendar events (or a")
+	fmt.Println("                        range's, with -from/-to, -last or -this; -only-work too)")
+	fmt.Println("  -export md            Print the day's or range's report as Markdown")
+	fmt.Println("  -export csv           Print the day's or range's activities as CSV")
+	fmt.Println("  -merge file.json      Merge another data file into the active one")
+	fmt.Println("  -rename OLD NEW       Replace the name prefix OLD with NEW in every entry (asks")
+	fmt.Println("                        first; -dry-run only counts)")
+	fmt.Println("  -archive              Move entries older than archive_after_days into archive/")
+	fmt.Println("  -audit                Print the log of changes (needs audit_log)")
+	fmt.Println("  -check                Report entries out of order, logged twice or after long gaps")
+	fmt.Println("  -reflect [text]       Write a reflection for the day (prompts if no text)")
+	fmt.Println("  -date YYYY-MM-DD      Target day for -r, -reflect and -import-calendar (default today)")
+	fmt.Println("  -profiles             List profiles (switch with p in the TUI)")
+	fmt.Println("  -p <profile>          Use a profile for this run (created on first use)")
+	fmt.Println("  -data-file path       Use this data file for this run")
+	fmt.Println("  -config-dir dir       Read config.json (and keep profiles) in this directory")
+	fmt.Println("  -no-emoji             Print plain ASCII instead of emoji")
+	fmt.Println("  -h                    Show this help")
+	fmt.Println()
+	fmt.Println("ENVIRONMENT:")
+	fmt.Println("  TT_CONFIG_DIR         Directory of config.json (default ~/.config/timetracker)")
+	fmt.Println("  TT_DATA_FILE          Data file, overriding data_file and profiles")
+	fmt.Println("  NO_COLOR              Print reports without colour")
+	fmt.Println("  A flag wins over its variable, which wins over config.json:")
+	fmt.Println("  -data-file > TT_DATA_FILE > data_file, -config-dir > TT_CONFIG_DIR")
+	fmt.Println()
+	fmt.Println("EXAMPLES:")
+	fmt.Println("  tt -s                 # Start your day")
+	fmt.Println("  tt -a \"Meeting: Standup\"")
+	fmt.Printf("  %-21s # Break task\n", fmt.Sprintf("tt -a %q", "Lunch "+names.breakMarker))
+	fmt.Println("  tt -a \"Dev work\" -c \"Fixed login bug\"")
+	fmt.Println("  tt -r                 # View today's report")
+	fmt.Println("  tt -r -last 7d        # Report over the last 7 days")
+	fmt.Println("  tt -r -this month     # Report for the current month")
+	fmt.Println("  tt -sprint current    # Report for the sprint under way")
+	fmt.Println("  tt -hours -from 2025-01-01 -to 2025-01-31")
+	fmt.Println("  tt -x                 # Extend last task")
+	fmt.Println()
+	fmt.Println("TASK TYPES:")
+	fmt.Println("  Regular task:    \"Meeting: Standup\"")
+	fmt.Printf("  Break task:      %q\n", "Lunch "+names.breakMarker)
+	fmt.Printf("  Ignored task:    %q\n", "Commuting "+names.ignoredMarker)
+	fmt.Println("  Or set it explicitly: tt -a \"Lunch\" -type break")
+}
+
+// normalizeOptions selects which clean-up steps -normalize applies
+type normalizeOptions struct {
+	Sort     bool // Order entries by timestamp
+	Trim     bool // Trim whitespace and write type markers as " **" / " ***"
+	Dedupe   bool // Drop exact duplicate entries
+	Collapse bool // Drop an entry repeated under the same name less than a minute later
+}
+
+// normalizeResult counts what each normalization step changed
+type normalizeResult struct {
+	Reordered  bool
+	Trimmed    int
+	Duplicates int
+	Collapsed  int
+}
+
+func (r normalizeResult) changed() bool {
+	return r.Reordered || r.Trimmed > 0 || r.Duplicates > 0 || r.Collapsed > 0
+}
+
+// normalizeEntries returns a canonical copy of entries along with a count of
+// what changed; the input slice is left untouched
+func normalizeEntries(entries []Entry, opts normalizeOptions, names nameParser) ([]Entry, normalizeResult) {
+	var result normalizeResult
+	cleaned := make([]Entry, 0, len(entries))
+	seen := make(map[string]bool)
+	
+	for _, entry := range entries {
+		if opts.Trim {
+			name, comment := names.canonicalName(entry.Name), strings.TrimSpace(entry.Comment)
+			if name != entry.Name || comment != entry.Comment {
+				result.Trimmed++
+			}
+			entry.Name, entry.Comment = name, comment
+		}
+		if opts.Dedupe {
+			key := entry.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + entry.Name + "\x00" + entry.Comment
+			if seen[key] {
+				result.Duplicates++
+				continue
+			}
+			seen[key] = true
+		}
+		cleaned = append(cleaned, entry)
+	}
+	
+	if opts.Sort {
+		result.Reordered = !sort.SliceIsSorted(cleaned, func(i, j int) bool {
+			return cleaned[i].Timestamp.Before(cleaned[j].Timestamp)
+		})
+		sortEntries(cleaned)
+	}
+	
+	if opts.Collapse {
+		collapsed := cleaned[:0]
+		for i, entry := range cleaned {
+			if i+1 < len(cleaned) {
+				next := cleaned[i+1]
+				if next.Name == entry.Name && next.Timestamp.Sub(entry.Timestamp) < time.Minute {
+					result.Collapsed++
+					continue
+				}
+			}
+			collapsed = append(collapsed, entry)
+		}
+		cleaned = collapsed
+	}
+	
+	return cleaned, result
+}
+
+// canonicalName trims a name, squeezes repeated spaces and writes any type
+// marker in its canonical form at the end, e.g. " **" / " ***"
+func (p nameParser) canonicalName(name string) string {
+	parsed := p.parseName(strings.Join(strings.Fields(name), " "))
+	switch parsed.Type {
+	case Break:
+		return parsed.Name + " " + p.breakMarker
+	case Ignored:
+		return parsed.Name + " " + p.ignoredMarker
+	}
+	return parsed.Name
+}
+
+// runNormalize cleans up the data file, printing what changed
+func runNormalize(tracker *TimeTracker, opts normalizeOptions, dryRun bool) error {
+	// Collapsing compares each entry with the next, which only means
+	// something in time order
+	if opts.Collapse && !opts.Sort {
+		return errors.New("-collapse needs the entries sorted, so it can't be used with -no-sort")
+	}
+	raw, err := readEntriesFile(tracker.config.DataFile)
+	if err != nil {
+		return err
+	}
+	
+	cleaned, result := normalizeEntries(raw, opts, tracker.config.parser())
+	if !result.changed() {
+		fmt.Printf("%sData file is already normalized.\n", iconDone)
+		return nil
+	}
+	
+	fmt.Println("Normalization summary:")
+	if result.Reordered {
+		fmt.Println("  Entries re-sorted by timestamp")
+	}
+	fmt.Printf("  Names/comments trimmed:    %d\n", result.Trimmed)
+	fmt.Printf("  Duplicates removed:        %d\n", result.Duplicates)
+	fmt.Printf("  Sub-minute repeats merged: %d\n", result.Collapsed)
+	fmt.Printf("  Entries:                   %d -> %d\n", len(raw), len(cleaned))
+	
+	if dryRun {
+		fmt.Println("Dry run: no changes written.")
+		return nil
+	}
+	
+	backup, err := tracker.backupDataFile()
+	if err != nil {
+		return fmt.Errorf("backing up data file: %w", err)
+	}
+	tracker.entries = cleaned
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	tracker.audit("normalize", fmt.Sprintf("%d -> %d entries (backup: %s)", len(raw), len(cleaned), backup))
+	fmt.Printf("%sData file normalized (backup: %s)\n", iconDone, backup)
+	return nil
+}
+
+// defaultCheckGap is the gap -check reports when idle_threshold_minutes is off
+const defaultCheckGap = 2 * time.Hour
+
+// anomaly is a problem -check found at an entry, by its index in the data file
+type anomaly struct {
+	Index   int
+	Entry   Entry
+	Problem string
+}
+
+// checkEntries diagnoses entries as stored, in file order: entries earlier
+// than the one before them, and, in the timestamp order loadEntries sorts
+// them into, double-logged entries and gaps over limit within a day that
+// count as work
+func checkEntries(show displayFormats, raw []Entry, limit time.Duration, names nameParser) []anomaly {
+	var found []anomaly
+	for i := 1; i < len(raw); i++ {
+		if raw[i].Timestamp.Before(raw[i-1].Timestamp) {
+			found = append(found, anomaly{i, raw[i], fmt.Sprintf("Out of order: earlier than #%d (%s)",
+				i-1, raw[i-1].Timestamp.Format("2006-01-02 15:04"))})
+		}
+	}
+	
+	// File indices in the order sortEntries would put the entries
+	order := make([]int, 0, len(raw))
+	for i, entry := range raw {
+		if entry.Action == "" {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return raw[order[a]].Timestamp.Before(raw[order[b]].Timestamp)
+	})
+	for k := 1; k < len(order); k++ {
+		prev, cur := raw[order[k-1]], raw[order[k]]
+		gap := cur.Timestamp.Sub(prev.Timestamp)
+		switch {
+		case gap == 0:
+			found = append(found, anomaly{order[k], cur, fmt.Sprintf("Logged at the same time as #%d (%s)", order[k-1], prev.Name)})
+		case gap > limit && prev.Name != "Stop" && dayKey(prev.Timestamp) == dayKey(cur.Timestamp) &&
+			names.parseActivity(cur, prev.Timestamp, cur.Timestamp, false).Type == Work:
+			found = append(found, anomaly{order[k], cur, fmt.Sprintf("%s since #%d (%s) with nothing logged in between",
+				show.span(gap), order[k-1], prev.Name)})
+		}
+	}
+	
+	sort.SliceStable(found, func(a, b int) bool {
+		return found[a].Index < found[b].Index
+	})
+	return found
+}
+
+// runCheck prints what checkEntries finds in the data file, changing nothing
+func runCheck(tracker *TimeTracker) error {
+	raw, err := readEntriesFile(tracker.config.DataFile)
+	if err != nil {
+		return err
+	}
+	limit := defaultCheckGap
+	if tracker.config.IdleThresholdMinutes > 0 {
+		limit = time.Duration(tracker.config.IdleThresholdMinutes) * time.Minute
+	}
+	
+	found := checkEntries(tracker.config.display(), raw, limit, tracker.config.parser())
+	if len(found) == 0 {
+		fmt.Printf("%sNo problems found in %d entries.\n", iconDone, len(raw))
+		return nil
+	}
+	
+	problems := "problems"
+	if len(found) == 1 {
+		problems = "problem"
+	}
+	fmt.Printf("Found %d %s in %d entries (#N is the index in %s):\n", len(found), problems, len(raw), tracker.config.DataFile)
+	for _, a := range found {
+		fmt.Printf("  #%-5d %s  %s\n", a.Index, a.Entry.Timestamp.Format("2006-01-02 15:04"), a.Entry.Name)
+		fmt.Printf("         %s\n", a.Problem)
+	}
+	return nil
+}
+
+// runMerge adds the entries of another data file to the active one. Exact
+// timestamp+name duplicates are skipped; entries within a minute of a
+// same-name entry are added but listed so they can be reviewed.
+func runMerge(tracker *TimeTracker, path string) error {
+	other, err := readEntriesFile(path)
+	if err != nil {
+		return err
+	}
+	
+	var added, near []Entry
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, entry := range other {
+		key := entry.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + entry.Name
+		if seen[key] || tracker.hasEntry(entry) {
+			skipped++
+			continue
+		}
+		seen[key] = true
+		for _, e := range tracker.entriesOn(entry.Timestamp) {
+			if gap := e.Timestamp.Sub(entry.Timestamp); e.Name == entry.Name && gap > -time.Minute && gap < time.Minute {
+				near = append(near, entry)
+				break
+			}
+		}
+		added = append(added, entry)
+	}
+	
+	fmt.Printf("Merging %s:\n", path)
+	fmt.Printf("  Added:   %d\n", len(added))
+	fmt.Printf("  Skipped: %d (already present)\n", skipped)
+	if len(near) > 0 {
+		fmt.Printf("Warning: %d added entries are within a minute of an existing entry with the same name:\n", len(near))
+		for _, entry := range near {
+			fmt.Printf("  %s  %s\n", entry.Timestamp.Format("2006-01-02 15:04:05"), entry.Name)
+		}
+		fmt.Println("Review them, or run 'tt -normalize -collapse' to merge them.")
+	}
+	if len(added) == 0 {
+		fmt.Println("Nothing to merge.")
+		return nil
+	}
+	
+	backup := "none, data file was empty"
+	if _, err := os.Stat(tracker.config.DataFile); err == nil {
+		if backup, err = tracker.backupDataFile(); err != nil {
+			return fmt.Errorf("backing up data file: %w", err)
+		}
+	}
+	tracker.entries = append(tracker.entries, added...)
+	sortEntries(tracker.entries)
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	tracker.audit("merge", fmt.Sprintf("%d entries from %s", len(added), path))
+	fmt.Printf("%sMerged into %s (backup: %s)\n", iconDone, tracker.config.DataFile, backup)
+	return nil
+}
+
+// renamePrefix works out the new name of every entry whose name starts with
+// old, by index. Start and Stop markers keep their names.
+func (tt *TimeTracker) renamePrefix(old, new string) (map[int]string, error) {
+	return prefixRenames(tt.entries, old, new)
+}
+
+// renamedArchives applies the rename to every archive file, returning the
+// renamed entries of each file that changes, by path, and how many changed.
+// Nothing is written.
+func (tt *TimeTracker) renamedArchives(old, new string) (map[string][]Entry, int, error) {
+	files, err := os.ReadDir(tt.archiveDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	archives := make(map[string][]Entry)
+	count := 0
+	for _, file := range files {
+		if _, err := time.Parse("2006-01.json", file.Name()); err != nil {
+			continue
+		}
+		path := filepath.Join(tt.archiveDir(), file.Name())
+		entries, err := readEntriesFile(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		renames, err := prefixRenames(entries, old, new)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(renames) == 0 {
+			continue
+		}
+		for i, name := range renames {
+			entries[i].Name = name
+		}
+		archives[path] = entries
+		count += len(renames)
+	}
+	return archives, count, nil
+}
+
+// prefixRenames is renamePrefix over any list of entries
+func prefixRenames(entries []Entry, old, new string) (map[int]string, error) {
+	if old == "" {
+		return nil, errors.New("the prefix to rename is empty")
+	}
+	renames := make(map[int]string)
+	for i, entry := range entries {
+		if entry.Name == "Start" || entry.Name == "Stop" || !strings.HasPrefix(entry.Name, old) {
+			continue
+		}
+		name := strings.TrimSpace(new + strings.TrimPrefix(entry.Name, old))
+		if name == "" {
+			return nil, fmt.Errorf("renaming %q would leave it without a name", entry.Name)
+		}
+		if name != entry.Name {
+			renames[i] = name
+		}
+	}
+	return renames, nil
+}
+
+// runRename replaces the prefix old with new in every entry name, archived
+// ones included, after showing how many change and asking. A dry run stops
+// after the count.
+func runRename(tracker *TimeTracker, old, new string, dryRun bool, in io.Reader) error {
+	renames, err := tracker.renamePrefix(old, new)
+	if err != nil {
+		return err
+	}
+	archives, archivedCount, err := tracker.renamedArchives(old, new)
+	if err != nil {
+		return fmt.Errorf("reading the archive: %w", err)
+	}
+	if len(renames) == 0 && archivedCount == 0 {
+		fmt.Printf("No entries start with %q\n", old)
+		return nil
+	}
+	
+	indexes := make([]int, 0, len(renames))
+	for i := range renames {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	total := len(renames) + archivedCount
+	switch {
+	case archivedCount == 0:
+		fmt.Printf("%d entries start with %q, for example:\n", total, old)
+	case len(renames) == 0:
+		fmt.Printf("%d archived entries start with %q\n", total, old)
+	default:
+		fmt.Printf("%d entries start with %q (%d of them archived), for example:\n", total, old, archivedCount)
+	}
+	for _, i := range indexes[:min(len(indexes), 5)] {
+		fmt.Printf("  %s  %s -> %s\n", tracker.entries[i].Timestamp.Format("2006-01-02 15:04"), tracker.entries[i].Name, renames[i])
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing changed.")
+		return nil
+	}
+	fmt.Printf("Rename them? [y/N] ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Println("Nothing renamed.")
+		return nil
+	}
+	
+	backup, err := tracker.backupDataFile()
+	if err != nil {
+		return fmt.Errorf("backing up data file: %w", err)
+	}
+	for path, entries := range archives {
+		if err := writeEntriesFile(path, entries); err != nil {
+			return err
+		}
+	}
+	tracker.archived, tracker.archivedMonths = nil, nil
+	tracker.intervals, tracker.dayActivities = nil, nil
+	if len(renames) > 0 {
+		for i, name := range renames {
+			tracker.entries[i].Name = name
+		}
+		if err := tracker.saveEntries(); err != nil {
+			return err
+		}
+	}
+	tracker.audit("rename", fmt.Sprintf("%d entries from %q to %q", total, old, new))
+	fmt.Printf("%sRenamed %d entries (backup: %s)\n", iconDone, total, backup)
+	return nil
+}
+
+// calendarEvent is the part of an ICS VEVENT that tt cares about
+type calendarEvent struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+}
+
+// parseICS extracts the VEVENTs from an iCalendar stream. Only SUMMARY,
+// DTSTART and DTEND are read; folded lines and TZID parameters are supported.
+func parseICS(r io.Reader) ([]calendarEvent, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Unfold continuation lines (RFC 5545 3.1)
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
 	
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		"",
-		prompt,
-		"",
-		input,
-		"",
-		message,
-		"",
-		help,
-	)
+	var events []calendarEvent
+	var current *calendarEvent
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			current = &calendarEvent{}
+			continue
+		case "END:VEVENT":
+			if current != nil {
+				if current.End.IsZero() {
+					current.End = current.Start
+				}
+				events = append(events, *current)
+			}
+			current = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		
+		prop, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(prop, ";")
+		switch strings.ToUpper(params[0]) {
+		case "SUMMARY":
+			current.Summary = unescapeICS(value)
+		case "DTSTART", "DTEND":
+			t, allDay, err := parseICSTime(value, params[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", params[0], err)
+			}
+			if strings.EqualFold(params[0], "DTSTART") {
+				current.Start, current.AllDay = t, allDay
+			} else {
+				current.End = t
+			}
+		}
+	}
+	return events, nil
+}
+
+// parseICSTime parses a DATE or DATE-TIME value, honouring a TZID parameter
+func parseICSTime(value string, params []string) (time.Time, bool, error) {
+	loc := time.Local
+	for _, param := range params {
+		name, v, _ := strings.Cut(param, "=")
+		switch strings.ToUpper(name) {
+		case "TZID":
+			if l, err := time.LoadLocation(strings.Trim(v, `"`)); err == nil {
+				loc = l
+			}
+		case "VALUE":
+			if strings.EqualFold(v, "DATE") {
+				t, err := time.ParseInLocation("20060102", value, time.Local)
+				return t, true, err
+			}
+		}
+	}
 	
-	return docStyle.Render(content)
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t.Local(), false, err
+	}
+	if len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t.Local(), false, err
 }
 
-func (m model) reportViewRender() string {
-	title := titleStyle.Render("📊 Today's Report")
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line ending in CRLF, folded so no line is
+// longer than 75 bytes (RFC 5545 3.1) without splitting a character
+func writeICSLine(w io.Writer, line string) error {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if _, err := io.WriteString(w, line[:cut]+"\r\n "); err != nil {
+			return err
+		}
+		// Continuation lines lose a byte to the leading space
+		line, limit = line[cut:], 74
+	}
+	_, err := io.WriteString(w, line+"\r\n")
+	return err
+}
+
+// icsUID identifies the event for an activity, stable across exports so
+// calendars update an imported event instead of adding it again
+func icsUID(activity Activity) string {
+	h := fnv.New32a()
+	h.Write([]byte(activity.Name))
+	return fmt.Sprintf("%s-%08x@tt", activity.Start.UTC().Format("20060102T150405Z"), h.Sum32())
+}
+
+// writeCSV writes activities as CSV rows of date, start, end, decimal hours,
+// project, task, type and comment under a header. Like writeICS it leaves
+// out the activity still running.
+func writeCSV(w io.Writer, activities []Activity) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "start", "end", "hours", "project", "task", "type", "comment"})
+	for _, activity := range activities {
+		if activity.IsCurrent {
+			continue
+		}
+		out.Write([]string{
+			dayKey(activity.Start),
+			activity.Start.Format("15:04"),
+			activity.End.Format("15:04"),
+			decimalHours(activity.Duration),
+			projectName(activity.Project),
+			activity.Task,
+			strings.ToLower(activity.Type.String()),
+			activity.Comment,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeICS writes activities as a VCALENDAR with an event per activity. The
+// activity still running has no end yet and is left out.
+func writeICS(w io.Writer, activities []Activity, now time.Time) error {
+	const layout = "20060102T150405Z"
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//tt//Time Tracker//EN", "CALSCALE:GREGORIAN"}
+	for _, activity := range activities {
+		if activity.IsCurrent {
+			continue
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+icsUID(activity),
+			"DTSTAMP:"+now.UTC().Format(layout),
+			"DTSTART:"+activity.Start.UTC().Format(layout),
+			"DTEND:"+activity.End.UTC().Format(layout),
+			"SUMMARY:"+escapeICS(activity.Name))
+		if activity.Project != "" {
+			lines = append(lines, "CATEGORIES:"+escapeICS(activity.Project))
+		}
+		if activity.Comment != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeICS(activity.Comment))
+		}
+		if activity.Type != Work {
+			// Breaks and ignored time don't make you busy
+			lines = append(lines, "TRANSP:TRANSPARENT")
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
 	
-	// Summary in viewport
-	summary := m.viewport.View()
+	for _, line := range lines {
+		if err := writeICSLine(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calendarEntries maps the timed events on day to entries logged at each
+// event's end, the way a meeting would have been logged by hand
+func (tt *TimeTracker) calendarEntries(events []calendarEvent, day time.Time) []Entry {
+	dayStart := startOfDay(day)
+	dayEnd := dayStart.AddDate(0, 0, 1)
 	
-	// Activities table
-	table := m.table.View()
+	var entries []Entry
+	for _, event := range events {
+		if event.AllDay || event.Start.Before(dayStart) || !event.Start.Before(dayEnd) {
+			continue
+		}
+		name := strings.TrimSpace(event.Summary)
+		if tt.config.CalendarProject != "" {
+			name = tt.config.CalendarProject + ": " + name
+		}
+		entries = append(entries, Entry{Timestamp: event.End, Name: name})
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// runCalendarImport offers the day's calendar events for import, letting the
+// user pick which ones become entries
+func runCalendarImport(tracker *TimeTracker, path string, day time.Time, in io.Reader) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 	
-	help := helpStyle.Render("Esc to go back • q to quit")
+	events, err := parseICS(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	candidates := tracker.calendarEntries(events, day)
+	if len(candidates) == 0 {
+		fmt.Printf("No timed events on %s in %s\n", day.Format("2006-01-02"), path)
+		return nil
+	}
 	
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		"",
-		summary,
-		"",
-		subtitleStyle.Render("Activities:"),
-		"",
-		table,
-		"",
-		help,
-	)
+	fmt.Printf("Events on %s:\n", day.Format("2006-01-02"))
+	for i, entry := range candidates {
+		fmt.Printf("  %d) %s  %s\n", i+1, tracker.config.display().clock(entry.Timestamp), entry.Name)
+	}
+	fmt.Print("Import which events? (e.g. 1,3; Enter for all, n for none): ")
 	
-	return docStyle.Render(content)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	selected, err := parseSelection(strings.TrimSpace(answer), len(candidates))
+	if err != nil {
+		return err
+	}
+	
+	var imported []Entry
+	for _, i := range selected {
+		entry := candidates[i]
+		if tracker.hasEntry(entry) {
+			continue
+		}
+		imported = append(imported, entry)
+	}
+	if len(imported) == 0 {
+		fmt.Println("Nothing imported.")
+		return nil
+	}
+	tracker.entries = append(tracker.entries, imported...)
+	sortEntries(tracker.entries)
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	for _, entry := range imported {
+		tracker.audit("import", entrySummary(entry))
+	}
+	fmt.Printf("%sImported %d calendar event(s)\n", iconDone, len(imported))
+	return nil
 }
 
-func (m model) helpViewRender() string {
-	title := titleStyle.Render("❓ Help")
+// gitCommit is one commit offered as a task by -git and the add-task view
+type gitCommit struct {
+	Hash    string
+	Time    time.Time
+	Subject string
+}
+
+// gitSince is where commit suggestions start: the last entry, or the start
+// of today when nothing was logged yet
+func (tt *TimeTracker) gitSince(now time.Time) time.Time {
+	if lastEntry, ok := tt.lastEntry(); ok {
+		return lastEntry.Timestamp
+	}
+	return startOfDay(now)
+}
+
+// gitCommits lists the commits of the repository around dir made after since,
+// oldest first. When git knows the user's email, only their commits count.
+func gitCommits(dir string, since time.Time) ([]gitCommit, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git is not installed")
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		abs, _ := filepath.Abs(dir)
+		return nil, fmt.Errorf("%s is not inside a git repository", abs)
+	}
 	
-	helpContent := `
-` + subtitleStyle.Render("Navigation:") + `
-  ↑/k, ↓/j     Move up/down
-  ←/h, →/l     Move left/right
-  Enter        Select/confirm
-  Esc          Go back
-  q            Quit
+	args := []string{"-C", dir, "log", "--no-merges", "--reverse",
+		"--since=" + since.Format(time.RFC3339), "--format=%h%x00%ct%x00%s"}
+	if email, err := exec.Command("git", "-C", dir, "config", "user.email").Output(); err == nil {
+		if email := strings.TrimSpace(string(email)); email != "" {
+			args = append(args, "--author="+email)
+		}
+	}
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		// A repository without commits yet has nothing to offer
+		return nil, nil
+	}
+	
+	var commits []gitCommit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		secs, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		commit := gitCommit{Hash: parts[0], Time: time.Unix(secs, 0), Subject: strings.TrimSpace(parts[2])}
+		if !commit.Time.After(since) || commit.Subject == "" {
+			continue
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
 
-` + subtitleStyle.Render("Actions:") + `
-  s            Start day
-  a            Complete task (add finished task)
-  r            View today's report
-  x            Extend last task to now
-  ?            Toggle this help
+// runGitImport offers the commits made since the last entry as tasks, each
+// logged at its commit time
+func runGitImport(tracker *TimeTracker, dir string, in io.Reader) error {
+	show := tracker.config.display()
+	since := tracker.gitSince(time.Now())
+	commits, err := gitCommits(dir, since)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Printf("No commits since %s %s\n", since.Format("2006-01-02"), show.clock(since))
+		return nil
+	}
+	
+	fmt.Printf("Commits since %s %s:\n", since.Format("2006-01-02"), show.clock(since))
+	for i, commit := range commits {
+		fmt.Printf("  %d) %s  %s\n", i+1, show.clock(commit.Time), commit.Subject)
+	}
+	fmt.Print("Log which commits as tasks? (e.g. 1,3; Enter for all, n for none): ")
+	
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	selected, err := parseSelection(strings.TrimSpace(answer), len(commits))
+	if err != nil {
+		return err
+	}
+	
+	var logged []Entry
+	for _, i := range selected {
+		commit := commits[i]
+		entry := Entry{Timestamp: commit.Time, Name: commit.Subject, Meta: map[string]string{"commit": commit.Hash}}
+		if tracker.hasEntry(entry) {
+			continue
+		}
+		logged = append(logged, entry)
+	}
+	if len(logged) == 0 {
+		fmt.Println("Nothing logged.")
+		return nil
+	}
+	tracker.entries = append(tracker.entries, logged...)
+	sortEntries(tracker.entries)
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	for _, entry := range logged {
+		tracker.audit("add", entrySummary(entry))
+	}
+	fmt.Printf("%sLogged %d commit(s) as tasks\n", iconDone, len(logged))
+	return nil
+}
 
-` + subtitleStyle.Render("Task Types:") + `
-  Regular task        "Meeting: Standup"
-  Break task (**)     "Lunch **"
-  Ignored task (***)  "Commuting ***"
+// summaryStartHour is when the synthetic day of a summary import begins
+const summaryStartHour = 9
 
-` + subtitleStyle.Render("Project Format:") + `
-  Use "Project: Task" to categorize activities
-  Examples: "Education: CKA Labs", "Sprint-2: Bug fix"
-`
+// summaryRow is one "date,hours,project" line of a summary import
+type summaryRow struct {
+	Day     time.Time
+	Hours   float64
+	Project string
+}
+
+// parseSummaryCSV reads "date,hours,project" rows. A first row whose hours
+// aren't a number is taken as a header and skipped.
+func parseSummaryCSV(r io.Reader) ([]summaryRow, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var rows []summaryRow
+	for i, record := range records {
+		if len(record) != 3 {
+			return nil, fmt.Errorf("line %d: want date,hours,project", i+1)
+		}
+		hours, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil && i == 0 {
+			continue
+		}
+		if err != nil || hours <= 0 {
+			return nil, fmt.Errorf("line %d: invalid hours %q", i+1, record[1])
+		}
+		day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(record[0]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q (use YYYY-MM-DD)", i+1, record[0])
+		}
+		rows = append(rows, summaryRow{Day: day, Hours: hours, Project: strings.TrimSpace(record[2])})
+	}
+	return rows, nil
+}
+
+// summaryEntries turns daily totals into synthetic entries: a Start at
+// summaryStartHour, then one entry per project laid end to end. The times
+// are made up, so every entry carries @source=summary.
+func summaryEntries(rows []summaryRow) []Entry {
+	var entries []Entry
+	ends := make(map[string]time.Time) // Where each day's next entry starts
+	meta := func() map[string]string { return map[string]string{"source": "summary"} }
+	for _, row := range rows {
+		key := dayKey(row.Day)
+		if _, ok := ends[key]; !ok {
+			ends[key] = row.Day.Add(summaryStartHour * time.Hour)
+			entries = append(entries, Entry{Timestamp: ends[key], Name: "Start", Meta: meta()})
+		}
+		name := "Imported total"
+		if row.Project != "" {
+			name = row.Project + ": " + name
+		}
+		ends[key] = ends[key].Add(hoursDuration(row.Hours))
+		entries = append(entries, Entry{Timestamp: ends[key], Name: name, Meta: meta()})
+	}
+	return entries
+}
+
+// runSummaryImport adds the entries for a summary file, leaving alone any
+// day that already has entries of its own
+func runSummaryImport(tracker *TimeTracker, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	
+	rows, err := parseSummaryCSV(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	
+	var imported []Entry
+	skipped := make(map[string]bool)
+	for _, entry := range summaryEntries(rows) {
+		key := dayKey(entry.Timestamp)
+		if !skipped[key] && len(tracker.entriesOn(entry.Timestamp)) > 0 {
+			skipped[key] = true
+			fmt.Printf("Skipped %s: it already has entries\n", key)
+		}
+		if !skipped[key] {
+			imported = append(imported, entry)
+		}
+	}
+	if len(imported) == 0 {
+		fmt.Println("Nothing imported.")
+		return nil
+	}
+	tracker.entries = append(tracker.entries, imported...)
+	sortEntries(tracker.entries)
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	for _, entry := range imported {
+		tracker.audit("import", entrySummary(entry))
+	}
+	fmt.Printf("%sImported %d entries with @source=summary\n", iconDone, len(imported))
+	return nil
+}
+
+// csvTimeLayouts are the timestamps -import reads besides RFC3339, in local time
+var csvTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// parseCSVTime reads an RFC3339 timestamp or one of csvTimeLayouts
+func parseCSVTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q (use RFC3339 or YYYY-MM-DD HH:MM)", value)
+}
+
+// parseEntriesCSV reads "timestamp,name,comment" rows, the comment being
+// optional. Malformed rows are returned as problems with their line number
+// instead of stopping the import. A first row whose timestamp doesn't parse
+// is taken as a header and skipped.
+func parseEntriesCSV(r io.Reader) ([]Entry, []string) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	
+	var entries []Entry
+	var problems []string
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				problems = append(problems, err.Error())
+				break
+			}
+			problems = append(problems, fmt.Sprintf("line %d: %v", parseErr.StartLine, parseErr.Err))
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			problems = append(problems, fmt.Sprintf("line %d: want timestamp,name,comment", line))
+			continue
+		}
+		t, err := parseCSVTime(record[0])
+		if err != nil {
+			if !first {
+				problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+			}
+			continue
+		}
+		name, meta := splitMeta(strings.TrimSpace(record[1]))
+		name, tags := splitTags(name)
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("line %d: empty name", line))
+			continue
+		}
+		entry := Entry{Timestamp: t, Name: name, Tags: tags, Meta: meta}
+		if len(record) == 3 {
+			entry.Comment = strings.TrimSpace(record[2])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, problems
+}
+
+// runCSVImport adds the rows of a timestamp,name,comment CSV file to the
+// data file, skipping entries already present (same timestamp and name)
+func runCSVImport(tracker *TimeTracker, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 	
-	back := helpStyle.Render("Press ? or Esc to go back")
+	rows, problems := parseEntriesCSV(f)
+	var added []Entry
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, entry := range rows {
+		key := entry.Timestamp.UTC().Format(time.RFC3339Nano) + "\x00" + entry.Name
+		if seen[key] || tracker.hasEntry(entry) {
+			skipped++
+			continue
+		}
+		seen[key] = true
+		added = append(added, entry)
+	}
 	
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		title,
-		helpContent,
-		"",
-		back,
-	)
+	fmt.Printf("Importing %s:\n", path)
+	fmt.Printf("  Added:   %d\n", len(added))
+	fmt.Printf("  Skipped: %d (already present)\n", skipped)
+	if len(problems) > 0 {
+		fmt.Printf("  Invalid: %d (not imported)\n", len(problems))
+		for _, problem := range problems {
+			fmt.Printf("    %s\n", problem)
+		}
+	}
+	if len(added) == 0 {
+		fmt.Println("Nothing imported.")
+		return nil
+	}
 	
-	return docStyle.Render(content)
+	backup := "none, data file was empty"
+	if _, err := os.Stat(tracker.config.DataFile); err == nil {
+		if backup, err = tracker.backupDataFile(); err != nil {
+			return fmt.Errorf("backing up data file: %w", err)
+		}
+	}
+	tracker.entries = append(tracker.entries, added...)
+	sortEntries(tracker.entries)
+	if err := tracker.saveEntries(); err != nil {
+		return err
+	}
+	tracker.audit("import", fmt.Sprintf("%d entries from %s", len(added), path))
+	fmt.Printf("%sImported into %s (backup: %s)\n", iconDone, tracker.config.DataFile, backup)
+	return nil
 }
 
-// TimeTracker methods
-func (tt *TimeTracker) loadConfig() {
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".config", "timetracker")
-	configFile := filepath.Join(configDir, "config.json")
-	
-	// Default config
-	tt.config = Config{
-		DataFile: filepath.Join(configDir, "entries.json"),
-		Editor:   "vi",
+// parseSelection turns "1,3", "" (all) or "n" (none) into zero-based indexes
+func parseSelection(answer string, count int) ([]int, error) {
+	var selected []int
+	switch strings.ToLower(answer) {
+	case "", "a", "all":
+		for i := 0; i < count; i++ {
+			selected = append(selected, i)
+		}
+		return selected, nil
+	case "n", "none":
+		return nil, nil
 	}
-	
-	// Try to load existing config
-	if data, err := os.ReadFile(configFile); err == nil {
-		json.Unmarshal(data, &tt.config)
-	} else {
-		// Create config directory and save default config
-		os.MkdirAll(configDir, 0755)
-		data, _ := json.MarshalIndent(tt.config, "", "  ")
-		os.WriteFile(configFile, data, 0644)
+	for _, part := range strings.Split(answer, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > count {
+			return nil, fmt.Errorf("invalid selection %q", strings.TrimSpace(part))
+		}
+		selected = append(selected, n-1)
 	}
+	return selected, nil
 }
 
-func (tt *TimeTracker) loadEntries() {
-	if data, err := os.ReadFile(tt.config.DataFile); err == nil {
-		json.Unmarshal(data, &tt.entries)
+// projectStats summarizes all the work ever logged against one project
+type projectStats struct {
+	Name      string
+	Total     time.Duration
+	Days      int
+	Sessions  int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// computeProjectStats gathers lifetime stats for a project (matched
+// case-insensitively, "General" meaning no project, and a client taking in
+// all of its projects). ok is false when the project never appears.
+func computeProjectStats(activities []Activity, project string) (stats projectStats, ok bool) {
+	days := make(map[string]bool)
+	for _, activity := range activities {
+		if activity.Type != Work || !inProject(activity.Project, project) {
+			continue
+		}
+		// Asked for a client, the stats are the client's
+		name := projectName(activity.Project)
+		if !strings.EqualFold(name, project) {
+			name = strings.TrimSpace(name[:projectSeparator(name)])
+		}
+		
+		if stats.Sessions == 0 || activity.Start.Before(stats.FirstSeen) {
+			stats.FirstSeen = activity.Start
+		}
+		if activity.End.After(stats.LastSeen) {
+			stats.LastSeen = activity.End
+		}
+		stats.Name = name
+		stats.Total += activity.counted()
+		stats.Sessions++
+		days[activity.Start.Format("2006-01-02")] = true
 	}
-	
-	// Sort entries by timestamp
-	sort.Slice(tt.entries, func(i, j int) bool {
-		return tt.entries[i].Timestamp.Before(tt.entries[j].Timestamp)
-	})
+	stats.Days = len(days)
+	return stats, stats.Sessions > 0
 }
 
-func (tt *TimeTracker) saveEntries() error {
-	// Ensure directory exists
-	dir := filepath.Dir(tt.config.DataFile)
-	os.MkdirAll(dir, 0755)
+// matches reports whether the activity's name or comment contains query,
+// ignoring case. An empty query matches everything.
+func (a Activity) matches(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(a.Name), query) || strings.Contains(strings.ToLower(a.Comment), query)
+}
+
+// printFind lists every activity whose name or comment contains query, with
+// its day, duration and project, returning false when none does
+func printFind(tracker *TimeTracker, query string) bool {
+	show := tracker.config.display()
+	var found []Activity
+	for _, activity := range tracker.getAllActivities() {
+		if activity.matches(query) {
+			found = append(found, activity)
+		}
+	}
+	if len(found) == 0 {
+		fmt.Printf("Nothing matches %q.\n", query)
+		return false
+	}
 	
-	data, err := json.MarshalIndent(tt.entries, "", "  ")
-	if err != nil {
-		return err
+	projectWidth := 0
+	for _, activity := range found {
+		projectWidth = max(projectWidth, lipgloss.Width(projectName(activity.Project)))
 	}
 	
-	return os.WriteFile(tt.config.DataFile, data, 0644)
+	var total time.Duration
+	for _, activity := range found {
+		total += activity.Duration
+		fmt.Printf("  %s  %s  %6s  %-*s  %s\n", activity.End.Format("Mon 2006-01-02"),
+			show.clockSpan(activity.Start, activity.End), show.duration(activity.Duration),
+			projectWidth, projectName(activity.Project), activity.Name)
+		if activity.Comment != "" {
+			fmt.Println(indentComment(activity.Comment, "      > "))
+		}
+	}
+	matches := "matches"
+	if len(found) == 1 {
+		matches = "match"
+	}
+	fmt.Printf("\n%d %s, %s in total\n", len(found), matches, show.duration(total))
+	return true
 }
 
-func (tt *TimeTracker) addEntry(entry Entry) error {
-	tt.entries = append(tt.entries, entry)
-	return tt.saveEntries()
+// inProject reports whether project is want, case-insensitively and with
+// "General" meaning none. A client also takes in its "Client: Project" projects.
+func inProject(project, want string) bool {
+	name := projectName(project)
+	if strings.EqualFold(name, want) {
+		return true
+	}
+	i := projectSeparator(name)
+	return i >= 0 && strings.EqualFold(strings.TrimSpace(name[:i]), strings.TrimSpace(want))
 }
 
-func (tt *TimeTracker) addStart() error {
-	entry := Entry{
-		Timestamp: time.Now(),
-		Name:      "Start",
+// canonicalProject writes a "Client:Project" project the way names parse,
+// with one space after each colon, so tag_project_map groups with them
+func canonicalProject(project string) string {
+	var levels []string
+	for i := projectSeparator(project); i >= 0; i = projectSeparator(project) {
+		levels = append(levels, strings.TrimSpace(project[:i]))
+		project = project[i+1:]
 	}
-	return tt.addEntry(entry)
+	return strings.Join(append(levels, strings.TrimSpace(project)), ": ")
 }
 
-func (tt *TimeTracker) extend() error {
-	if len(tt.entries) == 0 {
-		return fmt.Errorf("no entries to extend")
+// projectName is how a project is listed, "General" for none
+func projectName(project string) string {
+	if project == "" {
+		return "General"
 	}
-	
-	lastEntry := tt.entries[len(tt.entries)-1]
-	if lastEntry.Name == "Start" {
-		return fmt.Errorf("cannot extend start entry")
+	return project
+}
+
+// printProjectStats prints lifetime totals for one project, or the known
+// projects when it isn't found
+func printProjectStats(tracker *TimeTracker, project string) bool {
+	show := tracker.config.display()
+	activities := tracker.getAllActivities()
+	stats, ok := computeProjectStats(activities, project)
+	if !ok {
+		fmt.Printf("No work logged for project '%s'.\n", project)
+		known := sortedProjects(computeProjects(activities))
+		if len(known) > 0 {
+			fmt.Println("Known projects:")
+			for _, p := range known {
+				fmt.Printf("  %s\n", p.Name)
+			}
+		}
+		return false
 	}
 	
-	entry := Entry{
-		Timestamp: time.Now(),
-		Name:      lastEntry.Name,
-		Comment:   lastEntry.Comment,
+	title := fmt.Sprintf("%sProject: %s", iconProject, stats.Name)
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("=", utf8.RuneCountInString(title)))
+	fmt.Println()
+	fmt.Printf("Total:       %s\n", show.duration(stats.Total))
+	fmt.Printf("Days worked: %d\n", stats.Days)
+	fmt.Printf("Sessions:    %d (avg %s)\n", stats.Sessions, show.duration(stats.Total/time.Duration(stats.Sessions)))
+	fmt.Printf("First:       %s\n", stats.FirstSeen.Format("2006-01-02 15:04"))
+	fmt.Printf("Last:        %s\n", stats.LastSeen.Format("2006-01-02 15:04"))
+	return true
+}
+
+// runReflect shows the day's report and stores a reflection for it, prompting
+// for the text (with the existing one kept on an empty answer) when none is given
+func runReflect(tracker *TimeTracker, day time.Time, text string, in io.Reader) error {
+	if text == "" {
+		printDayReport(tracker, day, reportOptions{RoundTo: tracker.config.roundTo(), Merge: tracker.config.MergeConsecutive})
+		fmt.Println()
+		if existing := tracker.days[dayKey(day)].Reflection; existing != "" {
+			fmt.Print("New reflection (Enter to keep the current one): ")
+		} else {
+			fmt.Print("How did the day go? ")
+		}
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		text = strings.TrimSpace(answer)
+		if text == "" {
+			fmt.Println("Reflection unchanged.")
+			return nil
+		}
 	}
 	
-	return tt.addEntry(entry)
+	if err := tracker.setReflection(day, text); err != nil {
+		return err
+	}
+	fmt.Printf("%sReflection saved for %s\n", iconDone, day.Format("2006-01-02"))
+	return nil
 }
 
-func (tt *TimeTracker) getCurrentStatus() string {
-	if len(tt.entries) == 0 {
-		return infoStyle.Render("No activities yet. Start your day!")
+// parseFlags parses flags wherever they appear on the command line and
+// returns the positional arguments found between them
+func parseFlags(args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flag.CommandLine.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
-	
-	lastEntry := tt.entries[len(tt.entries)-1]
-	duration := time.Since(lastEntry.Timestamp)
-	
-	if lastEntry.Name == "Start" {
-		return currentActivityStyle.Render(fmt.Sprintf("Day started (%s ago)", 
-			formatDuration(duration)))
+}
+
+// commandAliases maps words people naturally type to the flag that does it
+var commandAliases = map[string]string{
+	"start":    "-s",
+	"stop":     "-e",
+	"end":      "-e",
+	"add":      "-a",
+	"comment":  "-c",
+	"reflect":  "-reflect",
+	"note":     "-note",
+	"report":   "-r",
+	"extend":   "-x",
+	"help":     "-h",
+	"profiles": "-profiles",
+}
+
+// flagErrorMessage turns a flag parsing error into a friendly hint
+func flagErrorMessage(err error) string {
+	const undefined = "flag provided but not defined: "
+	msg := err.Error()
+	if !strings.HasPrefix(msg, undefined) {
+		return fmt.Sprintf("Error: %s — run 'tt -h' for usage", msg)
 	}
 	
-	return currentActivityStyle.Render(fmt.Sprintf("Latest: %s (%s ago)", 
-		lastEntry.Name, formatDuration(duration)))
+	name := strings.TrimPrefix(msg, undefined)
+	msg = fmt.Sprintf("Unknown flag '%s' — run 'tt -h' for usage", name)
+	if suggestion := suggestCommand(name); suggestion != "" {
+		msg += fmt.Sprintf("\nDid you mean '%s'?", suggestion)
+	}
+	return msg
 }
 
-func (tt *TimeTracker) getRecentActivities(limit int) []Activity {
-	activities := tt.getTodaysActivities()
-	
-	if len(activities) > limit {
-		return activities[len(activities)-limit:]
+// unknownCommandMessage explains a stray positional argument
+func unknownCommandMessage(arg string) string {
+	msg := fmt.Sprintf("Unknown command '%s' — run 'tt -h' for usage", arg)
+	if suggestion := suggestCommand(arg); suggestion != "" {
+		msg += fmt.Sprintf("\nDid you mean '%s'?", suggestion)
 	}
-	return activities
+	return msg
 }
 
-func (tt *TimeTracker) getTodaysActivities() []Activity {
-	today := time.Now().Truncate(24 * time.Hour)
-	var todaysEntries []Entry
+// suggestCommand returns the flag whose name or alias is closest to input,
+// or "" when there is no single near match
+func suggestCommand(input string) string {
+	input = strings.ToLower(strings.TrimLeft(input, "-"))
+	if input == "" {
+		return ""
+	}
 	
-	// Get today's entries
-	for _, entry := range tt.entries {
-		if entry.Timestamp.After(today) {
-			todaysEntries = append(todaysEntries, entry)
+	best, bestDist, tied := "", -1, false
+	consider := func(word, target string) {
+		dist := levenshtein(input, word)
+		switch {
+		case bestDist == -1 || dist < bestDist:
+			best, bestDist, tied = target, dist, false
+		case dist == bestDist && target != best:
+			tied = true
 		}
 	}
-	
-	if len(todaysEntries) == 0 {
-		return []Activity{}
+	flag.VisitAll(func(f *flag.Flag) {
+		consider(f.Name, "-"+f.Name)
+	})
+	for word, target := range commandAliases {
+		consider(word, target)
 	}
 	
-	var activities []Activity
-	
-	// Convert entries to activities (each activity represents time between entries)
-	for i := 0; i < len(todaysEntries); i++ {
-		entry := todaysEntries[i]
-		
-		// Skip start entries - they don't represent completed work
-		if entry.Name == "Start" {
-			continue
+	// Only suggest when most of the input survived and the match is unambiguous
+	if tied || bestDist >= len(input) || bestDist > max(1, len(input)/3) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
 		}
-		
-		// Find the previous entry to calculate duration
-		var start time.Time
-		if i == 0 {
-			// If this is the first entry, we can't calculate duration
-			continue
-		} else {
-			start = todaysEntries[i-1].Timestamp
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// reportOptions are the transformations applied to activities before a
+// report is printed
+type reportOptions struct {
+	Anonymize bool                      // Replace names with pseudonyms and drop comments
+	Where     map[string]string         // Only keep activities with all of this metadata
+	Project   string                    // Only keep activities of this project ("General" for none)
+	OnlyWork  bool                      // Drop breaks and ignored activities
+	Billable  func(project string) bool // When set, only keep work on projects it accepts
+	JSON      bool                      // Print the report as one JSON object instead of text
+	RoundTo   time.Duration             // Round each activity's duration to this increment (0 = exact)
+	Heatmap   bool                      // Print work by hour of day instead of the report
+	Merge     bool                      // Coalesce back-to-back activities with the same name and type
+}
+
+func (o reportOptions) apply(activities []Activity) []Activity {
+	if o.Merge {
+		activities = mergeConsecutive(activities)
+	}
+	if len(o.Where) > 0 || o.OnlyWork || o.Billable != nil || o.Project != "" {
+		var matching []Activity
+		for _, activity := range activities {
+			if o.keep(activity) {
+				matching = append(matching, activity)
+			}
 		}
-		
-		end := entry.Timestamp
-		
-		activity := parseActivity(entry, start, end, false) // No "current" activities anymore
-		activities = append(activities, activity)
+		activities = matching
+	}
+	if o.RoundTo > 0 {
+		rounded := make([]Activity, len(activities))
+		for i, activity := range activities {
+			activity.Duration = roundedDuration(activity.Duration, o.RoundTo)
+			activity.Overlap = min(roundedDuration(activity.Overlap, o.RoundTo), activity.Duration)
+			rounded[i] = activity
+		}
+		activities = rounded
+	}
+	if o.Anonymize {
+		activities = anonymizeActivities(activities)
 	}
-	
 	return activities
 }
 
-func (tt *TimeTracker) getTodaysStats() struct {
-	WorkTime  time.Duration
-	BreakTime time.Duration
-	TotalTime time.Duration
-} {
-	activities := tt.getTodaysActivities()
-	
-	var workTime, breakTime time.Duration
-	
+// mergeConsecutive coalesces runs of timeline activities with the same name
+// and type where each starts as the previous one ends, as extending a task
+// leaves them. The merged activity spans the run, its durations are the sums,
+// and it keeps the distinct comments in order.
+func mergeConsecutive(activities []Activity) []Activity {
+	var merged []Activity
 	for _, activity := range activities {
-		switch activity.Type {
-		case Work:
-			workTime += activity.Duration
-		case Break:
-			breakTime += activity.Duration
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if !activity.Parallel && !last.Parallel && activity.Name == last.Name &&
+				activity.Type == last.Type && activity.Start.Equal(last.End) {
+				last.End = activity.End
+				last.Merged = true
+				last.Duration += activity.Duration
+				last.Overlap += activity.Overlap
+				last.IsCurrent = activity.IsCurrent
+				last.Tags = mergeTags(last.Tags, activity.Tags)
+				if activity.Comment != "" && !strings.Contains(last.Comment, activity.Comment) {
+					if last.Comment != "" {
+						last.Comment += "\n"
+					}
+					last.Comment += activity.Comment
+				}
+				continue
+			}
 		}
+		merged = append(merged, activity)
 	}
-	
-	return struct {
-		WorkTime  time.Duration
-		BreakTime time.Duration
-		TotalTime time.Duration
-	}{
-		WorkTime:  workTime,
-		BreakTime: breakTime,
-		TotalTime: workTime + breakTime,
+	return merged
+}
+
+// keep reports whether an activity passes the filters
+func (o reportOptions) keep(activity Activity) bool {
+	if len(o.Where) > 0 && !matchesMeta(activity.Meta, o.Where) {
+		return false
+	}
+	if o.Project != "" && !inProject(activity.Project, o.Project) {
+		return false
+	}
+	if (o.OnlyWork || o.Billable != nil) && activity.Type != Work {
+		return false
 	}
+	return o.Billable == nil || o.Billable(activity.Project)
 }
 
-func (tt *TimeTracker) getTodaysProjects() map[string]time.Duration {
-	activities := tt.getTodaysActivities()
-	projects := make(map[string]time.Duration)
+// anonymizeActivities replaces project and task names with stable pseudonyms
+// ("Project A", "Task 3") and drops comments, keeping times and types intact.
+// The same real name always maps to the same pseudonym within one call.
+func anonymizeActivities(activities []Activity) []Activity {
+	projects := make(map[string]string)
+	tasks := make(map[string]string)
 	
-	for _, activity := range activities {
-		if activity.Type == Work {
-			projects[activity.Project] += activity.Duration
+	anonymized := make([]Activity, len(activities))
+	for i, activity := range activities {
+		if activity.Project != "" {
+			if _, ok := projects[activity.Project]; !ok {
+				projects[activity.Project] = "Project " + letterName(len(projects))
+			}
+			activity.Project = projects[activity.Project]
+		}
+		if _, ok := tasks[activity.Task]; !ok {
+			tasks[activity.Task] = fmt.Sprintf("Task %d", len(tasks)+1)
+		}
+		activity.Task = tasks[activity.Task]
+		
+		activity.Name = activity.Task
+		if activity.Project != "" {
+			activity.Name = activity.Project + ": " + activity.Task
 		}
+		activity.Comment = ""
+		activity.Meta = nil
+		activity.Tags = nil
+		anonymized[i] = activity
 	}
-	
-	return projects
+	return anonymized
 }
 
-func (tt *TimeTracker) generateTodaysSummary() string {
-	stats := tt.getTodaysStats()
-	activities := tt.getTodaysActivities()
-	
-	var summary strings.Builder
-	
-	// Time summary
-	summary.WriteString(subtitleStyle.Render("Time Summary:") + "\n\n")
-	summary.WriteString(workStyle.Render(fmt.Sprintf("  Work:  %s\n", formatDuration(stats.WorkTime))))
-	summary.WriteString(breakStyle.Render(fmt.Sprintf("  Break: %s\n", formatDuration(stats.BreakTime))))
-	summary.WriteString(subtitleStyle.Render(fmt.Sprintf("  Total: %s\n\n", formatDuration(stats.TotalTime))))
-	
-	// Project breakdown
-	projects := make(map[string]time.Duration)
-	for _, activity := range activities {
-		if activity.Type == Work && activity.Project != "" {
-			projects[activity.Project] += activity.Duration
-		}
+// letterName returns A, B, ..., Z, AA, AB, ... for i = 0, 1, ...
+func letterName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
 	}
-	
-	if len(projects) > 0 {
-		summary.WriteString(subtitleStyle.Render("Projects:") + "\n\n")
-		for project, duration := range projects {
-			summary.WriteString(workStyle.Render(fmt.Sprintf("  %s: %s\n", project, formatDuration(duration))))
-		}
+	return name
+}
+
+func printTodaysReport(tracker *TimeTracker, opts reportOptions) {
+	printDayReport(tracker, time.Now(), opts)
+}
+
+// printDayReport prints the report for the day beginning at start, headed
+// by its reflection
+func printDayReport(tracker *TimeTracker, start time.Time, opts reportOptions) {
+	show := tracker.config.display()
+	title := iconReport.String() + "Today's Report"
+	if !start.Equal(tracker.todayStart(time.Now())) {
+		title = iconReport.String() + "Report: " + start.Format("Mon 2006-01-02")
 	}
-	
-	return summary.String()
+	var note string
+	if !opts.Anonymize {
+		note = tracker.days[dayKey(start)].Reflection
+	}
+	activities := opts.apply(tracker.activitiesOfDay(start))
+	if opts.JSON {
+		printJSONReport(show, dayKey(start), start, start.AddDate(0, 0, 1), note, activities)
+		return
+	}
+	if opts.Heatmap {
+		printHeatmap(show, title, activities)
+		return
+	}
+	printReport(tracker, title, note, tracker.emptyDayMessage(start), activities, false)
 }
 
-// Helper functions
-func parseActivity(entry Entry, start, end time.Time, isCurrent bool) Activity {
-	name := entry.Name
-	activityType := Work
-	project := ""
-	task := name
-	
-	// Determine activity type
-	if strings.HasSuffix(name, "***") {
-		activityType = Ignored
-		name = strings.TrimSuffix(name, " ***")
-		task = name
-	} else if strings.HasSuffix(name, "**") {
-		activityType = Break
-		name = strings.TrimSuffix(name, " **")
-		task = name
+// emptyDayMessage is shown in place of the activities of the day beginning
+// at start when it has none
+func (tt *TimeTracker) emptyDayMessage(start time.Time) string {
+	if start.Equal(tt.todayStart(time.Now())) {
+		return "No activities logged today."
+	}
+	return "No activities on " + dayKey(start) + "."
+}
+
+func printRangeReport(tracker *TimeTracker, label string, start, end time.Time, opts reportOptions) {
+	show := tracker.config.display()
+	title := fmt.Sprintf("%sReport: %s (%s to %s)", iconReport, label,
+		start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	activities := opts.apply(tracker.getActivitiesBetween(start, end))
+	if opts.JSON {
+		printJSONReport(show, label, start, end, "", activities)
+		return
+	}
+	if opts.Heatmap {
+		printHeatmap(show, title, activities)
+		return
+	}
+	printReport(tracker, title, "", "No activities logged in this range.", activities, true)
+}
+
+// jsonDuration is a duration for scripts (whole seconds) and for people
+type jsonDuration struct {
+	Seconds int64  `json:"seconds"`
+	Human   string `json:"human"`
+}
+
+func newJSONDuration(show displayFormats, d time.Duration) jsonDuration {
+	return jsonDuration{Seconds: int64(d / time.Second), Human: show.duration(d)}
+}
+
+type jsonProject struct {
+	Name     string       `json:"name"`
+	Duration jsonDuration `json:"duration"`
+}
+
+type jsonActivity struct {
+	Name     string            `json:"name"`
+	Project  string            `json:"project,omitempty"`
+	Task     string            `json:"task"`
+	Type     string            `json:"type"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Duration jsonDuration      `json:"duration"`
+	Comment  string            `json:"comment,omitempty"`
+	Parallel bool              `json:"parallel,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+}
+
+// jsonReport is the structure printed by tt -r json
+type jsonReport struct {
+	Label      string `json:"label"`
+	From       string `json:"from"`
+	To         string `json:"to"` // Last day included
+	Reflection string `json:"reflection,omitempty"`
+	Totals     struct {
+		Work  jsonDuration `json:"work"`
+		Break jsonDuration `json:"break"`
+		Total jsonDuration `json:"total"`
+	} `json:"totals"`
+	Projects   []jsonProject  `json:"projects"`
+	Tags       []jsonProject  `json:"tags"`
+	Activities []jsonActivity `json:"activities"`
+}
+
+// newJSONReport gathers the report over [start, end) for the JSON and
+// Markdown exports, using the same totals as the text report. Times are
+// whole seconds so jq's fromdateiso8601 can read them.
+func newJSONReport(show displayFormats, label string, start, end time.Time, note string, activities []Activity) jsonReport {
+	report := jsonReport{
+		Label:      label,
+		From:       dayKey(start),
+		To:         dayKey(end.AddDate(0, 0, -1)),
+		Reflection: note,
+		Projects:   []jsonProject{},
+		Tags:       []jsonProject{},
+		Activities: []jsonActivity{},
 	}
 	
-	// Parse project:task format
-	if strings.Contains(name, ":") {
-		parts := strings.SplitN(name, ":", 2)
-		if len(parts) == 2 {
-			project = strings.TrimSpace(parts[0])
-			task = strings.TrimSpace(parts[1])
-		}
+	stats := computeStats(activities)
+	report.Totals.Work = newJSONDuration(show, stats.WorkTime)
+	report.Totals.Break = newJSONDuration(show, stats.BreakTime)
+	report.Totals.Total = newJSONDuration(show, stats.TotalTime)
+	
+	for _, p := range sortedProjects(computeProjects(activities)) {
+		report.Projects = append(report.Projects, jsonProject{Name: p.Name, Duration: newJSONDuration(show, p.Duration)})
+	}
+	for _, t := range sortedProjects(computeTags(activities)) {
+		report.Tags = append(report.Tags, jsonProject{Name: t.Name, Duration: newJSONDuration(show, t.Duration)})
 	}
 	
-	return Activity{
-		Name:      name,
-		Start:     start,
-		End:       end,
-		Duration:  end.Sub(start),
-		Type:      activityType,
-		Project:   project,
-		Task:      task,
-		Comment:   entry.Comment,
-		IsCurrent: isCurrent,
+	for _, activity := range activities {
+		report.Activities = append(report.Activities, jsonActivity{
+			Name:     activity.Name,
+			Project:  activity.Project,
+			Task:     activity.Task,
+			Type:     strings.ToLower(activity.Type.String()),
+			Start:    activity.Start.Truncate(time.Second),
+			End:      activity.End.Truncate(time.Second),
+			Duration: newJSONDuration(show, activity.Duration),
+			Comment:  activity.Comment,
+			Parallel: activity.Parallel,
+			Meta:     activity.Meta,
+			Tags:     activity.Tags,
+		})
 	}
+	return report
 }
 
-func formatDuration(d time.Duration) string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	return fmt.Sprintf("%dh%02d", hours, minutes)
+// printJSONReport prints the report over [start, end) as JSON
+func printJSONReport(show displayFormats, label string, start, end time.Time, note string, activities []Activity) {
+	data, err := json.MarshalIndent(newJSONReport(show, label, start, end, note, activities), "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
 }
 
-func printCLIHelp() {
-	fmt.Println("tt - Time Tracker")
-	fmt.Println()
-	fmt.Println("USAGE:")
-	fmt.Println("  tt                    Start TUI interface")
-	fmt.Println("  tt [command]          Run command and exit")
-	fmt.Println()
-	fmt.Println("COMMANDS:")
-	fmt.Println("  -s                    Start your day")
-	fmt.Println("  -a \"task name\"        Add completed task")
-	fmt.Println("  -c \"comment\"          Add comment (use with -a)")
-	fmt.Println("  -r                    Show today's report")
-	fmt.Println("  -x                    Extend last task to now")
-	fmt.Println("  -h                    Show this help")
-	fmt.Println()
-	fmt.Println("EXAMPLES:")
-	fmt.Println("  tt -s                 # Start your day")
-	fmt.Println("  tt -a \"Meeting: Standup\"")
-	fmt.Println("  tt -a \"Lunch **\"      # Break task")
-	fmt.Println("  tt -a \"Dev work\" -c \"Fixed login bug\"")
-	fmt.Println("  tt -r                 # View today's report")
-	fmt.Println("  tt -x                 # Extend last task")
-	fmt.Println()
-	fmt.Println("TASK TYPES:")
-	fmt.Println("  Regular task:    \"Meeting: Standup\"")
-	fmt.Println("  Break task:      \"Lunch **\"")
-	fmt.Println("  Ignored task:    \"Commuting ***\"")
+// writeMarkdownReport writes report as Markdown: totals and projects as
+// tables, then the activities as a list
+func writeMarkdownReport(show displayFormats, w io.Writer, report jsonReport) {
+	cell := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+	
+	if report.From == report.To {
+		fmt.Fprintf(w, "# Report: %s\n\n", report.From)
+	} else {
+		fmt.Fprintf(w, "# Report: %s (%s to %s)\n\n", report.Label, report.From, report.To)
+	}
+	if report.Reflection != "" {
+		fmt.Fprintf(w, "%s\n\n", indentComment(report.Reflection, "> "))
+	}
+	
+	fmt.Fprintln(w, "| Totals | Time |")
+	fmt.Fprintln(w, "|---|---:|")
+	fmt.Fprintf(w, "| Work | %s |\n", report.Totals.Work.Human)
+	fmt.Fprintf(w, "| Break | %s |\n", report.Totals.Break.Human)
+	fmt.Fprintf(w, "| Total | %s |\n", report.Totals.Total.Human)
+	
+	if len(report.Projects) > 0 {
+		var total int64
+		for _, p := range report.Projects {
+			total += p.Duration.Seconds
+		}
+		fmt.Fprint(w, "\n## Projects\n\n| Project | Time | Share |\n|---|---:|---:|\n")
+		for _, p := range report.Projects {
+			share := 0.0
+			if total > 0 {
+				share = float64(p.Duration.Seconds) / float64(total) * 100
+			}
+			fmt.Fprintf(w, "| %s | %s | %.0f%% |\n", cell(p.Name), p.Duration.Human, share)
+		}
+	}
+	// An activity can carry several tags, so tags get no share
+	if len(report.Tags) > 0 {
+		fmt.Fprint(w, "\n## Tags\n\n| Tag | Time |\n|---|---:|\n")
+		for _, t := range report.Tags {
+			fmt.Fprintf(w, "| %s | %s |\n", cell(t.Name), t.Duration.Human)
+		}
+	}
+	
+	if len(report.Activities) == 0 {
+		return
+	}
+	fmt.Fprint(w, "\n## Activities\n\n")
+	for _, activity := range report.Activities {
+		when := show.clock(activity.Start) + "–" + show.clock(activity.End)
+		if report.From != report.To {
+			when = activity.End.Format("Mon 01-02") + " " + when
+		}
+		line := fmt.Sprintf("- %s **%s** (%s)", when, activity.Name, activity.Duration.Human)
+		if activity.Type != "work" {
+			line += " _" + activity.Type + "_"
+		}
+		if activity.Comment != "" {
+			line += " — " + cell(activity.Comment)
+		}
+		fmt.Fprintln(w, line)
+	}
 }
 
-func printTodaysReport(tracker *TimeTracker) {
-	activities := tracker.getTodaysActivities()
-	stats := tracker.getTodaysStats()
+// printReport prints totals, projects and activities under a title and an
+// optional note (the day's reflection), or empty when there are no
+// activities; multiDay adds the date to each activity line
+func printReport(tracker *TimeTracker, title, note, empty string, activities []Activity, multiDay bool) {
+	show := tracker.config.display()
+	stats := computeStats(activities)
 	
-	fmt.Println("📊 Today's Report")
-	fmt.Println("================")
+	fmt.Println(paint(subtitleStyle, title))
+	fmt.Println(paint(subtitleStyle, strings.Repeat("=", utf8.RuneCountInString(title))))
+	if note != "" {
+		fmt.Println(indentComment(note, "> "))
+	}
 	fmt.Println()
 	
 	// Summary
-	fmt.Printf("Work:  %s\n", formatDuration(stats.WorkTime))
-	fmt.Printf("Break: %s\n", formatDuration(stats.BreakTime))
-	fmt.Printf("Total: %s\n", formatDuration(stats.TotalTime))
+	fmt.Println(paint(workStyle, "Work:  "+show.duration(stats.WorkTime)))
+	fmt.Println(paint(breakStyle, "Break: "+show.duration(stats.BreakTime)))
+	fmt.Printf("Total: %s\n", show.duration(stats.TotalTime))
+	if tracker.config.IncludeIgnored {
+		fmt.Println(paint(ignoredStyle, fmt.Sprintf("Elapsed: %s (with %s ignored)",
+			show.duration(stats.Elapsed()), show.duration(stats.IgnoredTime))))
+	}
+	if target := tracker.config.DailyTargetHours; !multiDay && target > 0 {
+		fmt.Printf("Target: %s\n", targetProgress(show, stats.WorkTime, hoursDuration(target)))
+	}
+	if !multiDay && len(activities) > 0 {
+		if note := tracker.longDayNote(startOfDay(activities[len(activities)-1].End)); note != "" {
+			fmt.Println(note)
+		}
+	}
 	fmt.Println()
 	
+	// Work split across the day
+	if tracker.config.SplitDay {
+		split := tracker.splitDayTotals(activities)
+		fmt.Println(paint(subtitleStyle, "Work by Time of Day:"))
+		for i, label := range dayPartLabels {
+			fmt.Printf("  %-10s %s\n", label+":", show.duration(split[i]))
+		}
+		fmt.Println()
+	}
+	
 	// Projects
-	projects := tracker.getTodaysProjects()
+	projects := computeProjects(activities)
 	if len(projects) > 0 {
-		fmt.Println("Projects:")
-		for project, duration := range projects {
-			if project == "" {
-				project = "General"
+		fmt.Println(paint(subtitleStyle, "Projects:"))
+		lines := formatProjectLines(show, sortedProjects(projects), tracker.config.ProjectDepth > 1)
+		for i, line := range lines {
+			style := workStyle
+			if i == len(lines)-1 {
+				style = subtitleStyle
 			}
-			fmt.Printf("  %s: %s\n", project, formatDuration(duration))
+			fmt.Println(paint(style, line))
+		}
+		fmt.Println()
+	}
+	
+	// Tags
+	if tags := computeTags(activities); len(tags) > 0 {
+		fmt.Println(paint(subtitleStyle, "Tags:"))
+		for _, line := range formatTagLines(show, sortedProjects(tags)) {
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+	
+	// Earnings
+	if tracker.config.showEarnings() && len(activities) > 0 {
+		for _, line := range tracker.config.earningsLines(activities) {
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+	
+	// Totals per day
+	if multiDay && len(activities) > 0 {
+		days, stats := dailyStats(activities)
+		fmt.Println(paint(subtitleStyle, "Days:"))
+		for _, day := range days {
+			fmt.Printf("  %s  Work: %s  Break: %s\n", day.Format("Mon 2006-01-02"),
+				show.duration(stats[dayKey(day)].WorkTime), show.duration(stats[dayKey(day)].BreakTime))
+		}
+		fmt.Println()
+	}
+	
+	// Regular time versus overtime
+	cfg := tracker.config
+	if cfg.StandardDailyHours > 0 || (multiDay && cfg.StandardWeeklyHours > 0) {
+		fmt.Println(paint(subtitleStyle, "Overtime:"))
+		if multiDay && cfg.StandardWeeklyHours > 0 {
+			weekStart := cfg.weekStartDay()
+			regular, overtime := overtimeSplit(activities, hoursDuration(cfg.StandardWeeklyHours), func(t time.Time) string {
+				return dayKey(startOfWeek(t, weekStart))
+			})
+			fmt.Printf("  Regular:  %s  Overtime: %s  (beyond %s a week)\n",
+				show.duration(regular), show.duration(overtime), show.duration(hoursDuration(cfg.StandardWeeklyHours)))
+		}
+		if cfg.StandardDailyHours > 0 {
+			regular, overtime := overtimeSplit(activities, hoursDuration(cfg.StandardDailyHours), dayKey)
+			fmt.Printf("  Regular:  %s  Overtime: %s  (beyond %s a day)\n",
+				show.duration(regular), show.duration(overtime), show.duration(hoursDuration(cfg.StandardDailyHours)))
 		}
 		fmt.Println()
 	}
 	
+	// Counts
+	if len(activities) > 0 {
+		counts := computeCounts(activities)
+		fmt.Println(paint(subtitleStyle, "Stats:"))
+		fmt.Printf("  Activities: %d  Projects: %d  Context switches: %d  Average: %s\n",
+			counts.Activities, counts.Projects, counts.ContextSwitches, show.duration(counts.Average))
+		fmt.Println()
+	}
+	
 	// Activities
 	if len(activities) > 0 {
-		fmt.Println("Activities:")
+		fmt.Println(paint(subtitleStyle, "Activities:"))
 		for _, activity := range activities {
-			timeStr := activity.Start.Format("15:04") + "-" + activity.End.Format("15:04")
+			timeStr := show.clockSpan(activity.Start, activity.End)
+			if multiDay {
+				timeStr = activity.End.Format("Mon 01-02") + "  " + timeStr
+			}
 			typeStr := ""
 			switch activity.Type {
 			case Break:
@@ -1035,88 +7907,630 @@ func printTodaysReport(tracker *TimeTracker) {
 			case Ignored:
 				typeStr = " [IGNORED]"
 			}
+			if activity.Parallel {
+				typeStr += " [PARALLEL]"
+			}
 			
-			fmt.Printf("  %s  %s  %s%s\n", 
-				timeStr, 
-				formatDuration(activity.Duration), 
+			line := paint(typeStyle(activity.Type), fmt.Sprintf("  %s  %s  %s%s",
+				timeStr,
+				show.duration(activity.Duration),
 				activity.Name,
-				typeStr)
+				typeStr))
+			if over := tracker.config.breakOverrun(activity); over > 0 {
+				line += "  " + paint(warningStyle, overrunNote(show, over))
+			}
+			fmt.Println(line)
+			if activity.Comment != "" {
+				fmt.Println(paint(infoStyle, indentComment(activity.Comment, "      > ")))
+			}
+			if len(activity.Meta) > 0 {
+				fmt.Println("      " + formatMeta(activity.Meta))
+			}
 		}
 	} else {
-		fmt.Println("No activities logged today.")
+		fmt.Println(empty)
 	}
 }
 
 func main() {
-	// Parse command line flags
+	// Parse command line flags, reporting mistakes ourselves instead of the
+	// flag package's terse usage dump
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
 	var (
 		addTask    = flag.String("a", "", "Add a completed task")
 		startDay   = flag.Bool("s", false, "Start your day")
 		showReport = flag.Bool("r", false, "Show today's report")
 		extend     = flag.Bool("x", false, "Extend last task to current time")
 		showHelp   = flag.Bool("h", false, "Show help")
+		endDay     = flag.Bool("e", false, "End your day")
+		note       = flag.String("note", "", "Append a note to the comment of the last task")
 		comment    = flag.String("c", "", "Add comment to task (use with -a)")
+		lastRange  = flag.String("last", "", "Report over a trailing range: Nd, Nw, week, month")
+		thisRange  = flag.String("this", "", "Report over the current week, month or year")
+		force      = flag.Bool("force", false, "Extend even if the last entry was just logged (use with -x), or start again (with -s)")
+		normalize  = flag.Bool("normalize", false, "Clean up the data file")
+		dryRun     = flag.Bool("dry-run", false, "Show what -normalize would change without saving")
+		noSort     = flag.Bool("no-sort", false, "Skip re-sorting entries (use with -normalize)")
+		noTrim     = flag.Bool("no-trim", false, "Skip trimming names and markers (use with -normalize)")
+		noDedupe   = flag.Bool("no-dedupe", false, "Skip removing duplicates (use with -normalize)")
+		collapse   = flag.Bool("collapse", false, "Merge same-name entries less than a minute apart (use with -normalize)")
+		importCal  = flag.String("import-calendar", "", "Import meetings from an .ics file")
+		importSum  = flag.String("import-summary", "", "Import daily totals from a date,hours,project CSV file")
+		importCSV  = flag.String("import", "", "Import entries from a timestamp,name,comment CSV file")
+		export     = flag.String("export", "", "Print the day's or range's activities in another format: csv, ics or md")
+		project    = flag.String("project", "", "Only report or export activities of this project (\"General\" for none)")
+		gitImport  = flag.Bool("git", false, "Log commits made since the last entry as tasks")
+		rename     = flag.String("rename", "", "Replace this name prefix with the one given after it in every entry")
+		dateFlag   = flag.String("date", "", "Target day as YYYY-MM-DD (default today)")
+		anonymize  = flag.Bool("anonymize", false, "Replace project and task names with pseudonyms in reports")
+		openTask   = flag.String("open", "", "Start a parallel task (parallel_mode)")
+		closeTask  = flag.String("close", "", "Stop a running parallel task (parallel_mode)")
+		clockIn    = flag.String("in", "", "Clock in to a task, clocking out of the current one")
+		clockOut   = flag.Bool("out", false, "Clock out of the task clocked in with -in")
+		editFile   = flag.Bool("edit", false, "Open the data file in editor (or $EDITOR), then reload it")
+		projStats  = flag.String("project-stats", "", "Show lifetime stats for a project")
+		find       = flag.String("find", "", "List activities whose name or comment contains the text")
+		taskType   = flag.String("type", "", "Task type for -a: work, break or ignored (instead of ** / ***)")
+		reflect    = flag.Bool("reflect", false, "Write a reflection for the day (text as argument, or prompted)")
+		profiles   = flag.Bool("profiles", false, "List profiles with their data files and today's work")
+		profile    = flag.String("p", "", "Use this profile's data file for this run")
+		dataFile   = flag.String("data-file", "", "Use this data file instead of data_file and TT_DATA_FILE")
+		configDir  = flag.String("config-dir", "", "Read config.json from this directory instead of TT_CONFIG_DIR")
+		metaFlag   = flag.String("meta", "", "Metadata for -a as key=value pairs, comma separated")
+		noEmoji    = flag.Bool("no-emoji", false, "Print plain ASCII instead of emoji")
+		interact   = flag.Bool("i", false, "Preview and confirm before extending (use with -x), or offer to split idle time (with -a)")
+		onlyWork   = flag.Bool("only-work", false, "Leave breaks and ignored activities out of reports")
+		billable   = flag.Bool("billable", false, "Only report work on billable_projects")
+		mergeFile  = flag.String("merge", "", "Merge entries from another data file into the active one")
+		archive    = flag.Bool("archive", false, "Move entries older than archive_after_days into monthly archive files")
+		quickTask  = flag.Int("q", 0, "Log quick task N from quick_tasks")
+		showAudit  = flag.Bool("audit", false, "Print the audit log of changes (audit_log)")
+		check      = flag.Bool("check", false, "Report entries out of order, logged twice or after long gaps")
+		sprint     = flag.String("sprint", "", "Report over a sprint from sprints, or \"current\"")
+		fromDate   = flag.String("from", "", "First day of a report range as YYYY-MM-DD")
+		toDate     = flag.String("to", "", "Last day of a report range as YYYY-MM-DD (default today)")
+		undo       = flag.Bool("u", false, "Remove the most recent entry")
+		pace       = flag.Bool("pace", false, "Project when today's work reaches daily_target_hours")
+		hours      = flag.Bool("hours", false, "Print only the work hours of the day or range, as a decimal")
+		at         = flag.String("t", "", "When the task for -a finished: HH:MM today or RFC3339 (default now)")
+		ago        = flag.String("ago", "", "How long ago the task for -a finished, e.g. 30m or 1h15m")
+		where      = flag.String("where", "", "Only report activities whose metadata matches key=value pairs")
 	)
-	flag.Parse()
+	flag.BoolVar(force, "f", false, "Same as -force")
+	args, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			tracker := &TimeTracker{}
+			tracker.loadConfig() // Any config error shows with the next command
+			printCLIHelp(tracker.config.parser())
+			return
+		}
+		fmt.Println(flagErrorMessage(err))
+		os.Exit(2)
+	}
+	// tt -r week and tt -r month are short for -this week and -this month;
+	// tt -r json prints the report as JSON, tt -r heatmap work by hour of day
+	var jsonReport, heatmap bool
+	if *showReport {
+		var rest []string
+		for _, arg := range args {
+			switch {
+			case arg == "json":
+				jsonReport = true
+			case arg == "heatmap":
+				heatmap = true
+			case (arg == "week" || arg == "month") && *thisRange == "":
+				*thisRange = arg
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		args = rest
+	}
+	if len(args) > 0 && !*reflect && *rename == "" {
+		fmt.Println(unknownCommandMessage(args[0]))
+		os.Exit(2)
+	}
+	// -a "" was given on purpose, unlike leaving -a out, which opens the TUI
+	passed := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+	if passed["a"] && strings.TrimSpace(*addTask) == "" {
+		fmt.Println("Error: task name cannot be empty")
+		os.Exit(1)
+	}
 
 	// Handle CLI commands
-	if *showHelp {
-		printCLIHelp()
-		return
+	if *profile != "" {
+		if err := checkProfileName(*profile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		profileOverride = *profile
 	}
+	configDirOverride, dataFileOverride = *configDir, *dataFile
 
 	// Initialize tracker for CLI operations
 	tracker := &TimeTracker{}
-	tracker.loadConfig()
+	if err := tracker.loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config error, some settings use defaults: %v\n", err)
+	}
+	if *showHelp {
+		printCLIHelp(tracker.config.parser())
+		return
+	}
+	if err := tracker.checkDataDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nSet data_file in %s, or TT_DATA_FILE, to a writable location.\n", err, configFile())
+		os.Exit(1)
+	}
 	tracker.loadEntries()
+	tracker.loadDays()
+	emojiDisabled = *noEmoji || tracker.config.DisableEmoji
+	if tracker.corrupt != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n%s\n", tracker.corrupt, tracker.recoveryNote())
+		tracker.confirmOverwrite = func() bool {
+			fmt.Printf("Save over %s? The damaged file is kept next to it. [y/N] ", tracker.config.DataFile)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			return strings.EqualFold(strings.TrimSpace(answer), "y")
+		}
+	}
+	cliColor = stdoutIsTerminal() && os.Getenv("NO_COLOR") == ""
+
+	// Close days left open before they skew whatever this command records;
+	// -check only diagnoses, so it leaves the data as it is
+	if tracker.config.AutoClose && !*check {
+		if closures := tracker.staleClosures(time.Now()); len(closures) > 0 {
+			if err := tracker.closeStale(closures); err != nil {
+				fmt.Printf("Error closing day: %v\n", err)
+				os.Exit(1)
+			}
+			for _, line := range describeClosures(tracker.config.display(), closures) {
+				fmt.Println(iconNight.String() + line)
+			}
+		}
+	}
 
 	if *startDay {
-		err := tracker.addStart()
+		err := tracker.addStart(time.Now(), *force)
 		if err != nil {
 			fmt.Printf("Error starting day: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("✅ Day started!")
+		fmt.Printf("%sDay started!\n", iconDone)
+		return
+	}
+
+	if *endDay {
+		now := time.Now()
+		if err := tracker.addStop(now); err != nil {
+			fmt.Printf("Error ending day: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sDay ended at %s\n", iconNight, tracker.config.display().clock(now))
+		return
+	}
+
+	if *note != "" {
+		entry, err := tracker.addNote(*note)
+		if err != nil {
+			fmt.Printf("Error adding note: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sNote added to '%s' (%s): %s\n", iconDone, entry.Name, tracker.config.display().clock(entry.Timestamp), entry.Comment)
+		return
+	}
+
+	if *undo {
+		if _, ok := tracker.latestEntry(); !ok {
+			fmt.Println("Nothing to undo: there are no entries")
+			return
+		}
+		removed, err := tracker.undoLast()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sRemoved: %s (%s)\n", iconDone, removed.Name, removed.Timestamp.Format("2006-01-02 15:04"))
 		return
 	}
 
+	// A quick task is logged exactly like -a with its configured name
+	if *quickTask != 0 {
+		name, err := tracker.quickTask(*quickTask)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		*addTask = name
+	}
+
 	if *addTask != "" {
+		name, meta := splitMeta(tracker.config.expandShortcuts(*addTask))
+		name, tags := splitTags(name)
+		if !tracker.config.parser().hasTaskName(name) {
+			fmt.Println("Error: task name cannot be empty")
+			os.Exit(1)
+		}
+		if *metaFlag != "" {
+			pairs, err := parseMetaPairs(*metaFlag)
+			if err != nil {
+				fmt.Printf("Error: invalid -meta: %v\n", err)
+				os.Exit(1)
+			}
+			if meta == nil {
+				meta = pairs
+			}
+			for k, v := range pairs {
+				meta[k] = v
+			}
+		}
 		entry := Entry{
 			Timestamp: time.Now(),
-			Name:      *addTask,
+			Name:      name,
 			Comment:   *comment,
+			Meta:      meta,
+			Tags:      tags,
+		}
+		if *at != "" && *ago != "" {
+			fmt.Println("Error: use only one of -t and -ago")
+			os.Exit(1)
+		}
+		if *at != "" || *ago != "" {
+			t, err := parseEntryTime(tracker.config.display(), *at, time.Now())
+			if *ago != "" {
+				var d time.Duration
+				d, err = time.ParseDuration(*ago)
+				if err != nil || d < 0 {
+					err = fmt.Errorf("invalid -ago %q (use e.g. 30m or 1h15m)", *ago)
+				}
+				t = time.Now().Add(-d)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := tracker.checkBackdate(t, *force); err != nil {
+				fmt.Printf("Error: %v; pass -f to add it anyway\n", err)
+				os.Exit(1)
+			}
+			entry.Timestamp = t
+		}
+		if *taskType != "" {
+			t, ok := parseActivityType(*taskType)
+			if !ok {
+				fmt.Printf("Error: invalid -type %q (use work, break or ignored)\n", *taskType)
+				os.Exit(1)
+			}
+			entry.Type = tracker.config.parser().explicitType(entry.Name, t)
+		}
+		
+		gap, idle := tracker.idleGap(entry)
+		split := false
+		if idle && *interact {
+			fmt.Print(idleQuestion(tracker.config.display(), gap) + " [y/N] ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			split = strings.EqualFold(strings.TrimSpace(answer), "y")
 		}
 		
-		err := tracker.addEntry(entry)
+		bells, err := tracker.logTask(entry)
+		if err == nil && split {
+			err = tracker.splitIdle(entry)
+		}
 		if err != nil {
 			fmt.Printf("Error adding task: %v\n", err)
 			os.Exit(1)
 		}
+		ringBell(bells)
 		
 		// Calculate and show duration
 		var durationMsg string
-		if len(tracker.entries) > 1 {
-			lastEntry := tracker.entries[len(tracker.entries)-2]
+		if lastEntry, ok := tracker.lastEntryBefore(entry.Timestamp); ok && lastEntry.Name != "Stop" {
 			duration := entry.Timestamp.Sub(lastEntry.Timestamp)
-			durationMsg = fmt.Sprintf(" (%s)", formatDuration(duration))
+			durationMsg = fmt.Sprintf(" (%s)", tracker.config.display().span(duration))
 		}
 		
-		fmt.Printf("✅ Task completed: %s%s\n", *addTask, durationMsg)
+		fmt.Printf("%sTask completed: %s%s\n", iconDone, entry.Name, durationMsg)
+		if idle && !*interact {
+			fmt.Printf("Warning: nothing was logged for %s before this task. To split that off as idle time, undo with -u and add it again with -i.\n", tracker.config.display().span(gap))
+		}
+		return
+	}
+
+	if *openTask != "" {
+		if err := tracker.openParallel(*openTask, *comment); err != nil {
+			fmt.Printf("Error starting parallel task: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sStarted parallel task: %s\n", iconDone, *openTask)
+		return
+	}
+
+	if *closeTask != "" {
+		duration, err := tracker.closeParallel(*closeTask)
+		if err != nil {
+			fmt.Printf("Error stopping parallel task: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sStopped parallel task: %s (%s)\n", iconDone, *closeTask, tracker.config.display().span(duration))
+		return
+	}
+
+	if *editFile {
+		cmd, err := tracker.editDataFile()
+		if err != nil {
+			fmt.Printf("Error backing up data file: %v\n", err)
+			os.Exit(1)
+		}
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := tracker.finishEdit(cmd.Run()); err != nil {
+			fmt.Printf("Error editing data file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sReloaded %d entries from %s\n", iconDone, len(tracker.entries), tracker.config.DataFile)
+		return
+	}
+
+	if *clockIn != "" {
+		if err := tracker.clockIn(tracker.config.expandShortcuts(*clockIn), *comment); err != nil {
+			fmt.Printf("Error clocking in: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sClocked in: %s\n", iconDone, *clockIn)
+		return
+	}
+
+	if *clockOut {
+		entry, duration, err := tracker.clockOut()
+		if err != nil {
+			fmt.Printf("Error clocking out: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sClocked out: %s (%s)\n", iconDone, entry.Name, tracker.config.display().span(duration))
 		return
 	}
 
 	if *extend {
-		err := tracker.extend()
+		if *interact {
+			entry, from, err := tracker.extendEntry(*force, time.Now())
+			if err != nil {
+				fmt.Printf("Error extending task: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(extendPreview(tracker.config.display(), entry, from) + " [y/N] ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+				fmt.Println("Nothing extended.")
+				return
+			}
+		}
+		err := tracker.extend(*force)
 		if err != nil {
 			fmt.Printf("Error extending task: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("✅ Task extended to current time!")
+		fmt.Printf("%sTask extended to current time!\n", iconDone)
+		return
+	}
+
+	// Resolve the target day for date-aware commands, as the time it begins
+	targetDay := tracker.todayStart(time.Now())
+	if *dateFlag != "" {
+		day, err := time.ParseInLocation("2006-01-02", *dateFlag, time.Local)
+		if err != nil {
+			fmt.Printf("Error: invalid -date %q (use YYYY-MM-DD)\n", *dateFlag)
+			os.Exit(1)
+		}
+		targetDay = tracker.dayStartOn(day)
+	}
+
+	reportOpts := reportOptions{Anonymize: *anonymize, OnlyWork: *onlyWork, JSON: jsonReport, Heatmap: heatmap, RoundTo: tracker.config.roundTo(), Merge: tracker.config.MergeConsecutive}
+	if *billable {
+		reportOpts.Billable = tracker.config.isBillable
+	}
+	if *where != "" {
+		pairs, err := parseMetaPairs(*where)
+		if err != nil {
+			fmt.Printf("Error: invalid -where: %v\n", err)
+			os.Exit(1)
+		}
+		reportOpts.Where = pairs
+	}
+	reportOpts.Project = strings.TrimSpace(*project)
+
+	if *reflect {
+		if err := runReflect(tracker, targetDay, strings.Join(args, " "), os.Stdin); err != nil {
+			fmt.Printf("Error saving reflection: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *importCal != "" {
+		if err := runCalendarImport(tracker, *importCal, targetDay, os.Stdin); err != nil {
+			fmt.Printf("Error importing calendar: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *gitImport {
+		if err := runGitImport(tracker, ".", os.Stdin); err != nil {
+			fmt.Printf("Error reading commits: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *importSum != "" {
+		if err := runSummaryImport(tracker, *importSum); err != nil {
+			fmt.Printf("Error importing summary: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *pace {
+		if tracker.config.DailyTargetHours <= 0 {
+			fmt.Println("Error: set daily_target_hours in the config to project a pace")
+			os.Exit(1)
+		}
+		fmt.Println(tracker.pace(time.Now()))
+		return
+	}
+
+	if *profiles {
+		printProfiles(tracker)
+		return
+	}
+
+	if *check {
+		if err := runCheck(tracker); err != nil {
+			fmt.Printf("Error checking data file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *showAudit {
+		if err := printAudit(tracker); err != nil {
+			fmt.Printf("Error reading audit log: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *find != "" {
+		if !printFind(tracker, *find) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *projStats != "" {
+		if !printProjectStats(tracker, *projStats) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *importCSV != "" {
+		if err := runCSVImport(tracker, *importCSV); err != nil {
+			fmt.Printf("Error importing CSV: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *archive {
+		if err := runArchive(tracker, time.Now()); err != nil {
+			fmt.Printf("Error archiving entries: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *rename != "" {
+		if len(args) != 1 {
+			fmt.Println("Error: give the new prefix too: tt -rename \"Old:\" \"New:\"")
+			os.Exit(2)
+		}
+		if err := runRename(tracker, *rename, args[0], *dryRun, os.Stdin); err != nil {
+			fmt.Printf("Error renaming entries: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *mergeFile != "" {
+		if err := runMerge(tracker, *mergeFile); err != nil {
+			fmt.Printf("Error merging data file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *normalize {
+		opts := normalizeOptions{
+			Sort:     !*noSort,
+			Trim:     !*noTrim,
+			Dedupe:   !*noDedupe,
+			Collapse: *collapse,
+		}
+		if err := runNormalize(tracker, opts, *dryRun); err != nil {
+			fmt.Printf("Error normalizing data file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Resolve the range reported on, if any
+	var start, end time.Time
+	var label string
+	switch {
+	case *fromDate != "" || *toDate != "":
+		start, end, err = parseFromTo(*fromDate, *toDate, time.Now())
+		label = "custom range"
+	case *sprint != "":
+		var s Sprint
+		s, start, end, err = tracker.config.findSprint(*sprint, time.Now())
+		label = s.Name
+	case *lastRange != "" && *thisRange != "":
+		err = errors.New("use only one of -last and -this")
+	case *lastRange != "":
+		start, end, label, err = parseRangeSpec("last", *lastRange, time.Now(), tracker.config.weekStartDay())
+	case *thisRange != "":
+		start, end, label, err = parseRangeSpec("this", *thisRange, time.Now(), tracker.config.weekStartDay())
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Nothing but the number, for scripts
+	if *hours {
+		if start.IsZero() {
+			start, end = targetDay, targetDay.AddDate(0, 0, 1)
+		}
+		writeHours(os.Stdout, tracker, start, end, reportOpts)
+		return
+	}
+
+	if *export != "" {
+		if start.IsZero() {
+			start, end = targetDay, targetDay.AddDate(0, 0, 1)
+			label = dayKey(targetDay)
+		}
+		activities := reportOpts.apply(tracker.activitiesIn(start, end))
+		switch *export {
+		case "csv":
+			err = writeCSV(os.Stdout, activities)
+		case "ics":
+			err = writeICS(os.Stdout, activities, time.Now())
+		case "md":
+			// A single day is headed by its reflection, like -r
+			var note string
+			if !reportOpts.Anonymize && end.Equal(start.AddDate(0, 0, 1)) {
+				note = tracker.days[dayKey(start)].Reflection
+			}
+			show := tracker.config.display()
+			writeMarkdownReport(show, os.Stdout, newJSONReport(show, label, start, end, note, activities))
+		default:
+			err = fmt.Errorf("unknown export format %q (use csv, ics or md)", *export)
+		}
+		if err != nil {
+			fmt.Printf("Error exporting: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !start.IsZero() {
+		printRangeReport(tracker, label, start, end, reportOpts)
 		return
 	}
 
 	if *showReport {
-		printTodaysReport(tracker)
+		printDayReport(tracker, targetDay, reportOpts)
 		return
 	}
 
@@ -0,0 +1,1534 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTracker returns a tracker with the default config, reading and
+// writing only under a temporary directory, holding entries
+func newTestTracker(t *testing.T, entries ...Entry) *TimeTracker {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("TT_CONFIG_DIR", dir)
+	t.Setenv("TT_DATA_FILE", filepath.Join(dir, "entries.json"))
+	tt := &TimeTracker{}
+	if err := tt.loadConfig(); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	tt.entries = append([]Entry(nil), entries...)
+	sortEntries(tt.entries)
+	return tt
+}
+
+// at is 2025-03-10 (a Monday) at the given clock time, plus days
+func at(clock string, days ...int) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", "2025-03-10 "+clock, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	for _, d := range days {
+		t = t.AddDate(0, 0, d)
+	}
+	return t
+}
+
+func TestClockedPairsBoundTheTimeline(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Timestamp: at("01:00"), Name: "Start"},
+		Entry{Timestamp: at("01:00"), Name: "Support", Action: actionIn},
+		Entry{Timestamp: at("03:00"), Name: "Support", Action: actionOut},
+		Entry{Timestamp: at("03:30"), Name: "Email"},
+	)
+	activities := tt.getDayActivities(at("12:00"))
+	want := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"Support", at("01:00"), at("03:00")},
+		{"Email", at("03:00"), at("03:30")},
+	}
+	if len(activities) != len(want) {
+		t.Fatalf("got %d activities, want %d: %+v", len(activities), len(want), activities)
+	}
+	for i, w := range want {
+		a := activities[i]
+		if a.Name != w.name || !a.Start.Equal(w.start) || !a.End.Equal(w.end) {
+			t.Errorf("activity %d = %s %s-%s, want %s %s-%s", i, a.Name, a.Start.Format("15:04"), a.End.Format("15:04"),
+				w.name, w.start.Format("15:04"), w.end.Format("15:04"))
+		}
+	}
+	if work := computeStats(activities).WorkTime; work != 150*time.Minute {
+		t.Errorf("work = %s, want 2h30m", work)
+	}
+}
+
+func TestClockOutEndsTheEvening(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Timestamp: at("22:00"), Name: "Start"},
+		Entry{Timestamp: at("22:00"), Name: "Deploy", Action: actionIn},
+		Entry{Timestamp: at("23:00"), Name: "Deploy", Action: actionOut},
+		Entry{Timestamp: at("00:30", 1), Name: "Email"},
+	)
+	for _, a := range tt.getDayActivities(at("12:00")) {
+		if a.Name == "Email" {
+			t.Errorf("Email counted before midnight: %s-%s", a.Start.Format("15:04"), a.End.Format("15:04"))
+		}
+	}
+}
+
+func TestDataFileOverridesWinOverProfiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("TT_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"profile": "work"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env, flagged := filepath.Join(dir, "env.json"), filepath.Join(dir, "flag.json")
+	tests := []struct {
+		name, env, flag, want string
+	}{
+		{"profile", "", "", filepath.Join(dir, "work", "entries.json")},
+		{"TT_DATA_FILE", env, "", env},
+		{"-data-file", env, flagged, flagged},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TT_DATA_FILE", test.env)
+			dataFileOverride = test.flag
+			defer func() { dataFileOverride = "" }()
+			tt := &TimeTracker{}
+			tt.loadConfig()
+			if tt.config.DataFile != test.want {
+				t.Errorf("data file = %s, want %s", tt.config.DataFile, test.want)
+			}
+		})
+	}
+}
+
+func TestHasTaskName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"Email", true},
+		{"Acme: Call @ticket=42 +urgent", true},
+		{"@ticket=42", false},
+		{"+urgent", false},
+		{"**", false},
+		{"  ", false},
+	}
+	for _, test := range tests {
+		name, _ := splitMeta(test.input)
+		if got := newTestTracker(t).config.parser().hasTaskName(name); got != test.want {
+			t.Errorf("hasTaskName(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestStreak(t *testing.T) {
+	day := func(offset int, hours float64) []Entry {
+		start := at("09:00", offset)
+		return []Entry{
+			{Timestamp: start, Name: "Start"},
+			{Timestamp: start.Add(hoursDuration(hours)), Name: "Work"},
+		}
+	}
+	var past []Entry
+	past = append(past, day(-4, 8)...) // Thu
+	past = append(past, day(-3, 2)...) // Fri, missed
+	past = append(past, day(-2, 8)...) // Sat
+	past = append(past, day(-1, 8)...) // Sun
+	tests := []struct {
+		today        float64
+		skipWeekends bool
+		want         int
+	}{
+		{8, false, 3},
+		{2, false, 2}, // Today doesn't break the streak before it's over
+		{8, true, 1},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t, append(past, day(0, test.today)...)...)
+		tt.config.DailyTargetHours = 8
+		tt.config.SkipWeekends = test.skipWeekends
+		if got := tt.streak(at("20:00")); got != test.want {
+			t.Errorf("streak(today %vh, skip_weekends %v) = %d, want %d", test.today, test.skipWeekends, got, test.want)
+		}
+	}
+}
+
+func TestIdleGap(t *testing.T) {
+	yesterday := []Entry{
+		{Timestamp: at("09:00", -1), Name: "Start"},
+		{Timestamp: at("17:00", -1), Name: "Work"},
+	}
+	late := []Entry{
+		{Timestamp: at("20:00", -1), Name: "Start"},
+		{Timestamp: at("23:00", -1), Name: "Work"},
+	}
+	tests := []struct {
+		name      string
+		entries   []Entry
+		autoStart bool
+		task      time.Time
+		want      time.Duration
+		idle      bool
+	}{
+		{"first task of the morning", yesterday, false, at("09:30"), 0, false},
+		{"first task after an auto Start", yesterday, true, at("09:30"), 15 * time.Minute, false},
+		{"carried on from last night", late, false, at("01:30"), 150 * time.Minute, true},
+		{"long gap in the day", append(yesterday, Entry{Timestamp: at("09:00"), Name: "Start"}), false, at("11:00"), 2 * time.Hour, true},
+		{"after a Stop", append(yesterday, Entry{Timestamp: at("18:00", -1), Name: "Stop"}), false, at("09:30"), 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTracker(t, test.entries...)
+			tt.config.IdleThresholdMinutes = 60
+			tt.config.AutoStartDay = test.autoStart
+			tt.config.AutoStartMinutes = 15
+			gap, idle := tt.idleGap(Entry{Timestamp: test.task, Name: "Email"})
+			if gap != test.want || idle != test.idle {
+				t.Errorf("idleGap = %s, %v; want %s, %v", gap, idle, test.want, test.idle)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.json")
+	if err := os.WriteFile(private, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target.json")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, path, written string
+		mode                os.FileMode
+	}{
+		{"new file", filepath.Join(dir, "new.json"), filepath.Join(dir, "new.json"), 0644},
+		{"keeps the mode", private, private, 0600},
+		{"follows a symlink", link, target, 0644},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := writeFileAtomic(test.path, []byte("new"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(test.written)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != test.mode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), test.mode)
+			}
+			if data, _ := os.ReadFile(test.written); string(data) != "new" {
+				t.Errorf("%s holds %q, want %q", test.written, data, "new")
+			}
+		})
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link.json is no longer a symlink")
+	}
+}
+
+func TestMergedRowsCantBeEdited(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Timestamp: at("09:00"), Name: "Start"},
+		Entry{Timestamp: at("10:00"), Name: "Email"},
+		Entry{Timestamp: at("10:30"), Name: "Email"},
+		Entry{Timestamp: at("11:00"), Name: "Call"},
+	)
+	activities := mergeConsecutive(tt.getDayActivities(at("12:00")))
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"Email", true},
+		{"Call", false},
+	}
+	for _, test := range tests {
+		for _, activity := range activities {
+			if activity.Name != test.name {
+				continue
+			}
+			if _, err := tt.entryIndex(activity); (err != nil) != test.wantErr {
+				t.Errorf("entryIndex(%s) error = %v, want error %v", test.name, err, test.wantErr)
+			}
+		}
+	}
+}
+
+func TestDayStartHour(t *testing.T) {
+	entries := []Entry{
+		{Timestamp: at("23:00", -1), Name: "Start"},
+		{Timestamp: at("02:00"), Name: "Release"},
+	}
+	tests := []struct {
+		dayStartHour int
+		work         time.Duration
+		streak       int
+		pace         string
+	}{
+		{0, 2 * time.Hour, 0, "At current pace you'll hit 3h00 by 03:30."},
+		{4, 3 * time.Hour, 1, "Goal met: 3h00 of 3h00 today."},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t, entries...)
+		tt.config.DayStartHour = test.dayStartHour
+		tt.config.DailyTargetHours = 3
+		now := at("03:00")
+		if work := computeStats(tt.activitiesOfDay(tt.todayStart(now))).WorkTime; work != test.work {
+			t.Errorf("day_start_hour %d: work today = %s, want %s", test.dayStartHour, work, test.work)
+		}
+		if got := tt.streak(now); got != test.streak {
+			t.Errorf("day_start_hour %d: streak = %d, want %d", test.dayStartHour, got, test.streak)
+		}
+		if got := tt.pace(now); got != test.pace {
+			t.Errorf("day_start_hour %d: pace = %q, want %q", test.dayStartHour, got, test.pace)
+		}
+		report := captureStdout(t, func() {
+			printDayReport(tt, tt.todayStart(now), reportOptions{})
+		})
+		if want := "Work:  " + tt.config.display().duration(test.work); !strings.Contains(report, want) {
+			t.Errorf("day_start_hour %d: report doesn't show %q:\n%s", test.dayStartHour, want, report)
+		}
+		// Yesterday's 23:00 Start began today when today began at 4am
+		if err := tt.addStart(now, false); (err != nil) != (test.dayStartHour == 4) {
+			t.Errorf("day_start_hour %d: addStart = %v", test.dayStartHour, err)
+		}
+	}
+}
+
+// captureStdout returns what f prints
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// naiveEntriesBetween is the linear scan entriesBetween replaced
+func naiveEntriesBetween(entries []Entry, start, end time.Time) []Entry {
+	var found []Entry
+	for _, entry := range entries {
+		if !entry.Timestamp.Before(start) && entry.Timestamp.Before(end) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// manyEntries is a sorted history of n entries, one every 20 minutes up to
+// the end of day
+func manyEntries(n int, day time.Time) []Entry {
+	entries := make([]Entry, n)
+	last := day.Add(24*time.Hour - time.Minute)
+	for i := range entries {
+		entries[i] = Entry{Timestamp: last.Add(-time.Duration(n-1-i) * 20 * time.Minute), Name: "Task"}
+	}
+	return entries
+}
+
+func TestEntriesBetween(t *testing.T) {
+	entries := manyEntries(500, at("00:00"))
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"today", at("00:00"), at("00:00", 1)},
+		{"a week", at("00:00", -6), at("00:00", 1)},
+		{"an hour", at("10:00", -2), at("11:00", -2)},
+		{"on an entry", at("10:00", -2), at("10:20", -2)},
+		{"before everything", at("00:00", -30), at("00:00", -20)},
+		{"after everything", at("00:00", 1), at("00:00", 2)},
+		{"empty", at("12:00"), at("12:00")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := entriesBetween(entries, test.start, test.end)
+			want := naiveEntriesBetween(entries, test.start, test.end)
+			if len(got) != len(want) {
+				t.Fatalf("got %d entries, want %d", len(got), len(want))
+			}
+			for i := range got {
+				if !got[i].Timestamp.Equal(want[i].Timestamp) {
+					t.Errorf("entry %d at %s, want %s", i, got[i].Timestamp, want[i].Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTodaysActivities(b *testing.B) {
+	dir := b.TempDir()
+	b.Setenv("HOME", dir)
+	b.Setenv("TT_CONFIG_DIR", dir)
+	b.Setenv("TT_DATA_FILE", filepath.Join(dir, "entries.json"))
+	tt := &TimeTracker{}
+	tt.loadConfig()
+	tt.entries = manyEntries(50000, startOfDay(time.Now()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tt.dayActivities = nil // Time building the day, not the cache
+		tt.getTodaysActivities()
+	}
+}
+
+// The lookup of one day among 50k entries, binary search against the linear
+// scan it replaced. On a laptop: about 0.5µs against 370µs per lookup.
+func BenchmarkEntriesBetween(b *testing.B) {
+	entries := manyEntries(50000, at("00:00"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entriesBetween(entries, at("00:00"), at("00:00", 1))
+	}
+}
+
+func BenchmarkNaiveEntriesBetween(b *testing.B) {
+	entries := manyEntries(50000, at("00:00"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		naiveEntriesBetween(entries, at("00:00"), at("00:00", 1))
+	}
+}
+
+func TestStartlessDay(t *testing.T) {
+	entries := []Entry{
+		{Timestamp: at("09:00", -1), Name: "Start"},
+		{Timestamp: at("17:00", -1), Name: "Stop"},
+		{Timestamp: at("09:00"), Name: "Email"},
+		{Timestamp: at("10:00"), Name: "Call"},
+	}
+	tests := []struct {
+		requireStart bool
+		want         []string
+	}{
+		{false, []string{"Email 00:00-09:00", "Call 09:00-10:00"}},
+		{true, []string{"Call 09:00-10:00"}}, // Only the unbounded first task is dropped
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t, entries...)
+		tt.config.RequireStart = test.requireStart
+		var got []string
+		for _, a := range tt.getDayActivities(at("12:00")) {
+			got = append(got, a.Name+" "+a.Start.Format("15:04")+"-"+a.End.Format("15:04"))
+		}
+		if strings.Join(got, ", ") != strings.Join(test.want, ", ") {
+			t.Errorf("require_start %v: activities = %q, want %q", test.requireStart, got, test.want)
+		}
+	}
+}
+
+func TestParseName(t *testing.T) {
+	names := newTestTracker(t).config.parser()
+	custom := nameParser{breakMarker: "(break)", ignoredMarker: "(off)"}
+	nested := nameParser{breakMarker: "**", ignoredMarker: "***", projectDepth: 2}
+	tests := []struct {
+		names         nameParser
+		input         string
+		name          string
+		kind          ActivityType
+		project, task string
+		tags          []string
+	}{
+		{names, "Email", "Email", Work, "", "Email", nil},
+		{names, "Lunch **", "Lunch", Break, "", "Lunch", nil},
+		{names, "** Lunch", "Lunch", Break, "", "Lunch", nil},
+		{names, "Commute ***", "Commute", Ignored, "", "Commute", nil},
+		{names, "Acme: Call", "Acme: Call", Work, "Acme", "Call", nil},
+		{names, "Call at 3:00", "Call at 3:00", Work, "", "Call at 3:00", nil},
+		{names, "Acme: Fix #login bug #123 +urgent", "Acme: Fix #login bug #123", Work, "Acme", "Fix #login bug #123", []string{"login", "urgent"}},
+		{names, ": Call", ": Call", Work, "", ": Call", nil},
+		{names, "Acme: Web: Deploy", "Acme: Web: Deploy", Work, "Acme", "Web: Deploy", nil},
+		{nested, "Acme: Web: Deploy", "Acme: Web: Deploy", Work, "Acme: Web", "Deploy", nil},
+		{nested, "Acme: Deploy", "Acme: Deploy", Work, "Acme", "Deploy", nil},
+		{custom, "Lunch (break)", "Lunch", Break, "", "Lunch", nil},
+		{custom, "Commute (off)", "Commute", Ignored, "", "Commute", nil},
+		{custom, "Lunch **", "Lunch **", Work, "", "Lunch **", nil},
+	}
+	for _, test := range tests {
+		got := test.names.parseName(test.input)
+		if got.Name != test.name || got.Type != test.kind || got.Project != test.project || got.Task != test.task ||
+			strings.Join(got.Tags, ",") != strings.Join(test.tags, ",") {
+			t.Errorf("parseName(%q) = %+v, want name %q type %v project %q task %q tags %q",
+				test.input, got, test.name, test.kind, test.project, test.task, test.tags)
+		}
+	}
+}
+
+func TestDropBelowMinutes(t *testing.T) {
+	entries := []Entry{
+		{Timestamp: at("09:00"), Name: "Start"},
+		{Timestamp: at("09:03"), Name: "Slack"},
+		{Timestamp: at("10:00"), Name: "Email"},
+	}
+	tests := []struct {
+		dropBelow int
+		want      []string
+	}{
+		{0, []string{"Slack 09:00-09:03", "Email 09:03-10:00"}},
+		{3, []string{"Slack 09:00-09:03", "Email 09:03-10:00"}},
+		{5, []string{"Email 09:03-10:00"}},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t, entries...)
+		tt.config.DropBelowMinutes = test.dropBelow
+		var got []string
+		for _, a := range tt.getDayActivities(at("12:00")) {
+			got = append(got, a.Name+" "+a.Start.Format("15:04")+"-"+a.End.Format("15:04"))
+		}
+		if strings.Join(got, ", ") != strings.Join(test.want, ", ") {
+			t.Errorf("drop_below_minutes %d: activities = %q, want %q", test.dropBelow, got, test.want)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	tests := []struct {
+		auditLog bool
+		want     []string
+	}{
+		{false, nil},
+		{true, []string{"add        2025-03-10 10:00  Email (inbox)", "undo       2025-03-10 10:00  Email (inbox)"}},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t)
+		tt.config.AuditLog = test.auditLog
+		if err := tt.addTask(Entry{Timestamp: at("10:00"), Name: "Email", Comment: "inbox"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tt.undoLast(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(tt.auditFile())
+		if !test.auditLog {
+			if err == nil {
+				t.Errorf("audit_log off: audit.log written")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(test.want) {
+			t.Fatalf("audit.log has %d lines, want %d:\n%s", len(lines), len(test.want), data)
+		}
+		for i, line := range lines {
+			// Each line starts with when the change was made
+			if got := line[len("2006-01-02 15:04:05  "):]; got != test.want[i] {
+				t.Errorf("line %d = %q, want %q", i, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestTaskAcrossMidnight(t *testing.T) {
+	tests := []struct {
+		name             string
+		gapHours         int
+		entries          []Entry
+		yesterday, today time.Duration
+	}{
+		{"split at midnight", 4, []Entry{
+			{Timestamp: at("23:00", -1), Name: "Start"},
+			{Timestamp: at("23:50", -1), Name: "Email"},
+			{Timestamp: at("00:10"), Name: "Deploy"},
+		}, 60 * time.Minute, 10 * time.Minute},
+		{"after a Stop", 4, []Entry{
+			{Timestamp: at("23:00", -1), Name: "Start"},
+			{Timestamp: at("23:50", -1), Name: "Stop"},
+			{Timestamp: at("00:10"), Name: "Deploy"},
+		}, 0, 10 * time.Minute},
+		{"after a night off", 4, []Entry{
+			{Timestamp: at("18:00", -1), Name: "Start"},
+			{Timestamp: at("19:00", -1), Name: "Email"},
+			{Timestamp: at("08:00"), Name: "Deploy"},
+		}, 60 * time.Minute, 8 * time.Hour},
+		{"within a longer overnight_gap_hours", 14, []Entry{
+			{Timestamp: at("18:00", -1), Name: "Start"},
+			{Timestamp: at("19:00", -1), Name: "Email"},
+			{Timestamp: at("08:00"), Name: "Deploy"},
+		}, 6 * time.Hour, 8 * time.Hour},
+		{"with overnight_gap_hours off", 0, []Entry{
+			{Timestamp: at("23:00", -1), Name: "Start"},
+			{Timestamp: at("23:50", -1), Name: "Email"},
+			{Timestamp: at("00:10"), Name: "Deploy"},
+		}, 50 * time.Minute, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTracker(t, test.entries...)
+			tt.config.OvernightGapHours = test.gapHours
+			yesterday := computeStats(tt.getDayActivities(at("12:00", -1))).WorkTime
+			today := computeStats(tt.getDayActivities(at("12:00"))).WorkTime
+			if yesterday != test.yesterday || today != test.today {
+				t.Errorf("work = %s yesterday, %s today; want %s, %s", yesterday, today, test.yesterday, test.today)
+			}
+		})
+	}
+}
+
+func TestRoundedDuration(t *testing.T) {
+	tests := []struct {
+		d, increment, want time.Duration
+	}{
+		{7 * time.Minute, 15 * time.Minute, 0},
+		{7*time.Minute + 30*time.Second, 15 * time.Minute, 15 * time.Minute},
+		{8 * time.Minute, 15 * time.Minute, 15 * time.Minute},
+		{52 * time.Minute, 15 * time.Minute, 45 * time.Minute},
+		{53 * time.Minute, 15 * time.Minute, time.Hour},
+		{8 * time.Minute, 0, 8 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := roundedDuration(test.d, test.increment); got != test.want {
+			t.Errorf("roundedDuration(%s, %s) = %s, want %s", test.d, test.increment, got, test.want)
+		}
+	}
+}
+
+func TestDisplayFormats(t *testing.T) {
+	hm := displayFormats{clockLayout: "15:04", durationStyle: "hm"}
+	colon := displayFormats{clockLayout: "3:04PM", durationStyle: "colon"}
+	tests := []struct {
+		show           displayFormats
+		d              time.Duration
+		duration, span string
+	}{
+		{hm, 40 * time.Second, "40s", "40s"},
+		{hm, -5 * time.Minute, "-0h05", "-0h05"},
+		{hm, 90 * time.Minute, "1h30", "1h30"},
+		{hm, 26 * time.Hour, "26h00", "1d 2h00"},
+		{hm, 0, "0h00", "0h00"},
+		{colon, 26 * time.Hour, "26:00", "26:00"},
+		{displayFormats{durationStyle: "decimal"}, 90 * time.Minute, "1.50h", "1.50h"},
+	}
+	for _, test := range tests {
+		if got := test.show.duration(test.d); got != test.duration {
+			t.Errorf("%s duration(%s) = %q, want %q", test.show.durationStyle, test.d, got, test.duration)
+		}
+		if got := test.show.span(test.d); got != test.span {
+			t.Errorf("%s span(%s) = %q, want %q", test.show.durationStyle, test.d, got, test.span)
+		}
+	}
+	if got := colon.clockSpan(at("09:05"), at("13:30")); got != "  9:05AM-1:30PM" {
+		t.Errorf("clockSpan = %q, want %q", got, "  9:05AM-1:30PM")
+	}
+}
+
+func TestEntriesStaySorted(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []string // Clock times in the order they're added
+	}{
+		{"in order", []string{"09:00", "10:00", "11:00"}},
+		{"backdated", []string{"09:00", "11:00", "10:00"}},
+		{"before everything", []string{"10:00", "11:00", "09:00"}},
+		{"same time", []string{"09:00", "10:00", "10:00"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTracker(t)
+			for _, clock := range test.order {
+				if err := tt.addEntry("add", Entry{Timestamp: at(clock), Name: "Task " + clock}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !entriesSorted(tt.entries) {
+				t.Errorf("entries out of order in memory: %+v", tt.entries)
+			}
+			tt.loadEntries()
+			if len(tt.entries) != len(test.order) || !entriesSorted(tt.entries) {
+				t.Errorf("entries saved out of order: %+v", tt.entries)
+			}
+		})
+	}
+}
+
+func TestSaveSortsOutOfOrderEntries(t *testing.T) {
+	tt := newTestTracker(t)
+	tt.config.AuditLog = true
+	tt.entries = []Entry{
+		{Timestamp: at("11:00"), Name: "Call"},
+		{Timestamp: at("09:00"), Name: "Start"},
+	}
+	if err := tt.saveEntries(); err != nil {
+		t.Fatal(err)
+	}
+	tt.loadEntries()
+	if !entriesSorted(tt.entries) {
+		t.Errorf("entries saved out of order: %+v", tt.entries)
+	}
+	if data, _ := os.ReadFile(tt.auditFile()); !strings.Contains(string(data), "out of time order") {
+		t.Errorf("audit.log doesn't mention the sort:\n%s", data)
+	}
+}
+
+func TestParseRangeSpec(t *testing.T) {
+	now := at("15:00") // A Monday
+	tests := []struct {
+		kind, spec string
+		start, end time.Time
+		label      string
+		wantErr    bool
+	}{
+		{"last", "7d", at("00:00", -6), at("00:00", 1), "last 7 days", false},
+		{"last", "1d", at("00:00"), at("00:00", 1), "last 1 day", false},
+		{"last", "2w", at("00:00", -13), at("00:00", 1), "last 2 weeks", false},
+		{"last", "week", at("00:00", -7), at("00:00"), "last week", false},
+		{"this", "week", at("00:00"), at("00:00", 7), "this week", false},
+		{"last", "3660d", at("00:00", -3659), at("00:00", 1), "last 3660 days", false},
+		{"last", "3661d", time.Time{}, time.Time{}, "", true},
+		{"last", "523w", time.Time{}, time.Time{}, "", true},
+		{"last", "9999999999999999w", time.Time{}, time.Time{}, "", true},
+		{"last", "0d", time.Time{}, time.Time{}, "", true},
+		{"last", "7x", time.Time{}, time.Time{}, "", true},
+		{"this", "day", time.Time{}, time.Time{}, "", true},
+	}
+	for _, test := range tests {
+		start, end, label, err := parseRangeSpec(test.kind, test.spec, now, time.Monday)
+		if (err != nil) != test.wantErr {
+			t.Errorf("-%s %s: error = %v, want error %v", test.kind, test.spec, err, test.wantErr)
+			continue
+		}
+		if err == nil && (!start.Equal(test.start) || !end.Equal(test.end) || label != test.label) {
+			t.Errorf("-%s %s = %s to %s %q, want %s to %s %q", test.kind, test.spec,
+				start, end, label, test.start, test.end, test.label)
+		}
+	}
+}
+
+func TestLogTaskBells(t *testing.T) {
+	tests := []struct {
+		name      string
+		bell      bool
+		goalHours float64
+		want      int
+	}{
+		{"bell off", false, 1, 0},
+		{"task done", true, 0, 1},
+		{"weekly goal met", true, 1, 2},
+		{"weekly goal already met", true, 0.5, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTracker(t,
+				Entry{Timestamp: at("09:00"), Name: "Start"},
+				Entry{Timestamp: at("09:45"), Name: "Email"},
+			)
+			tt.config.BellOnComplete = test.bell
+			tt.config.WeeklyGoalHours = test.goalHours
+			got, err := tt.logTask(Entry{Timestamp: at("10:00"), Name: "Call"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("logTask rang %d times, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExtendEntry(t *testing.T) {
+	tests := []struct {
+		name   string
+		after  time.Duration
+		force  bool
+		wantOK bool
+	}{
+		{"10s after the last entry", 10 * time.Second, false, false},
+		{"10s after, forced", 10 * time.Second, true, true},
+		{"past min_extend_minutes", 2 * time.Minute, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTracker(t,
+				Entry{Timestamp: at("09:00"), Name: "Start"},
+				Entry{Timestamp: at("09:45"), Name: "Email", Comment: "inbox"},
+			)
+			now := at("09:45").Add(test.after)
+			entry, from, err := tt.extendEntry(test.force, now)
+			if (err == nil) != test.wantOK {
+				t.Fatalf("extendEntry = %v, want ok %v", err, test.wantOK)
+			}
+			if err != nil {
+				return
+			}
+			if entry.Name != "Email" || entry.Comment != "inbox" || !entry.Timestamp.Equal(now) || !from.Equal(at("09:45")) {
+				t.Errorf("extendEntry = %+v from %s, want Email at %s from 09:45", entry, from.Format("15:04"), now.Format("15:04:05"))
+			}
+		})
+	}
+}
+
+func TestClientMatchesItsProjects(t *testing.T) {
+	tt := newTestTracker(t)
+	tt.config.ProjectDepth = 2
+	tt.config.HourlyRate = 50
+	tt.config.BillableProjects = []string{"Acme"}
+	tt.config.ProjectRates = map[string]float64{"Acme": 100, "acme: web": 120}
+	tests := []struct {
+		project  string
+		billable bool
+		rate     float64
+	}{
+		{"Acme", true, 100},
+		{"Acme: Web", true, 120},
+		{"Acme: Mobile", true, 100},
+		{"Acmeish: Web", false, 0},
+		{"", false, 0},
+	}
+	for _, test := range tests {
+		if got := tt.config.isBillable(test.project); got != test.billable {
+			t.Errorf("isBillable(%q) = %v, want %v", test.project, got, test.billable)
+		}
+		if got := tt.config.rate(test.project); got != test.rate {
+			t.Errorf("rate(%q) = %v, want %v", test.project, got, test.rate)
+		}
+	}
+
+	activities := []Activity{
+		{Project: "Acme: Web", Type: Work, Start: at("09:00"), End: at("10:00"), Duration: time.Hour},
+		{Project: "Acme: Mobile", Type: Work, Start: at("10:00"), End: at("10:30"), Duration: 30 * time.Minute},
+		{Project: "Other", Type: Work, Start: at("10:30"), End: at("11:00"), Duration: 30 * time.Minute},
+	}
+	stats, ok := computeProjectStats(activities, "acme")
+	if !ok || stats.Name != "Acme" || stats.Total != 90*time.Minute || stats.Sessions != 2 {
+		t.Errorf("computeProjectStats(acme) = %+v, %v; want Acme, 1h30 over 2 sessions", stats, ok)
+	}
+
+	tt.config.TagProjectMap = map[string]string{"web": "Acme:Web"}
+	activity := tt.activity(Entry{Name: "Deploy #web"}, at("09:00"), at("10:00"))
+	if activity.Project != "Acme: Web" {
+		t.Errorf("tag_project_map gave project %q, want %q", activity.Project, "Acme: Web")
+	}
+}
+
+func TestHourlyTotalsUseCountedTime(t *testing.T) {
+	activities := []Activity{
+		{Type: Work, Start: at("09:40"), End: at("11:10"), Duration: 90 * time.Minute},
+		// Rounded down to half its wall-clock length
+		{Type: Work, Start: at("13:00"), End: at("14:00"), Duration: 30 * time.Minute},
+		// Runs alongside other work, so only a quarter of it counts
+		{Type: Work, Start: at("15:00"), End: at("16:00"), Duration: time.Hour, Overlap: 45 * time.Minute, Parallel: true},
+		{Type: Break, Start: at("12:00"), End: at("13:00"), Duration: time.Hour},
+	}
+	totals := hourlyTotals(activities)
+	want := map[int]time.Duration{9: 20 * time.Minute, 10: time.Hour, 11: 10 * time.Minute, 13: 30 * time.Minute, 15: 15 * time.Minute}
+	for hour, d := range totals {
+		if d != want[hour] {
+			t.Errorf("hour %d = %v, want %v", hour, d, want[hour])
+		}
+	}
+}
+
+func TestLongTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		now     time.Time
+		long    bool
+	}{
+		{"running past the limit", []Entry{{Name: "Start", Timestamp: at("09:00")}}, at("10:30"), true},
+		{"within the limit", []Entry{{Name: "Start", Timestamp: at("09:00")}}, at("09:30"), false},
+		{"stopped", []Entry{{Name: "Start", Timestamp: at("09:00")}, {Name: "Stop", Timestamp: at("09:10")}}, at("10:30"), false},
+		{"left over from yesterday", []Entry{{Name: "Start", Timestamp: at("17:00", -1)}}, at("09:00"), false},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t, test.entries...)
+		tt.config.LongTaskMinutes = 60
+		if _, _, long := tt.longTask(test.now); long != test.long {
+			t.Errorf("%s: long = %v, want %v", test.name, long, test.long)
+		}
+	}
+}
+
+func TestRenameReachesArchive(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Name: "Start", Timestamp: at("09:00", -40)},
+		Entry{Name: "Old: Design", Timestamp: at("10:00", -40)},
+		Entry{Name: "Start", Timestamp: at("09:00")},
+		Entry{Name: "Old: Build", Timestamp: at("10:00")},
+		Entry{Name: "Other", Timestamp: at("11:00")},
+	)
+	if moved, err := tt.archiveEntries(at("00:00", -1)); err != nil || moved != 2 {
+		t.Fatalf("archiveEntries = %d, %v; want 2 moved", moved, err)
+	}
+	if err := runRename(tt, "Old:", "New:", false, strings.NewReader("y\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if tt.entries[1].Name != "New: Build" || tt.entries[2].Name != "Other" {
+		t.Errorf("data file names = %q, %q; want New: Build, Other", tt.entries[1].Name, tt.entries[2].Name)
+	}
+	archived, err := readEntriesFile(tt.archiveFile(at("00:00", -40)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 2 || archived[1].Name != "New: Design" {
+		t.Errorf("archive = %+v, want New: Design renamed", archived)
+	}
+}
+
+func TestCollapseNeedsSort(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Name: "Task", Timestamp: at("09:00")},
+		Entry{Name: "Task", Timestamp: at("09:00").Add(30 * time.Second)},
+	)
+	err := runNormalize(tt, normalizeOptions{Collapse: true, Trim: true, Dedupe: true}, true)
+	if err == nil || !strings.Contains(err.Error(), "-no-sort") {
+		t.Errorf("runNormalize(-collapse -no-sort) = %v, want an error naming -no-sort", err)
+	}
+}
+
+func TestEditEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    Entry
+		input    string
+		wantName string
+		wantType string
+		wantMeta map[string]string
+	}{
+		{"metadata is split out and merged", Entry{Name: "Fix", Meta: map[string]string{"ticket": "1"}}, "Fix login @location=home", "Fix login", "", map[string]string{"ticket": "1", "location": "home"}},
+		{"metadata is overridden", Entry{Name: "Fix", Meta: map[string]string{"ticket": "1"}}, "Fix @ticket=2", "Fix", "", map[string]string{"ticket": "2"}},
+		{"explicit type kept with the same markers", Entry{Name: "Lunch", Type: "break"}, "Long lunch", "Long lunch", "break", nil},
+		{"new marker drops the explicit type", Entry{Name: "Lunch", Type: "work"}, "Lunch **", "Lunch **", "", nil},
+		{"removed marker drops the explicit type", Entry{Name: "Lunch **", Type: "ignored"}, "Lunch", "Lunch", "", nil},
+	}
+	for _, test := range tests {
+		test.entry.Timestamp = at("12:00")
+		tt := newTestTracker(t, Entry{Name: "Start", Timestamp: at("09:00")}, test.entry)
+		if err := tt.editEntry(1, test.input, ""); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got := tt.entries[1]
+		if got.Name != test.wantName || got.Type != test.wantType || len(got.Meta) != len(test.wantMeta) {
+			t.Errorf("%s: got %q type %q meta %v; want %q type %q meta %v", test.name, got.Name, got.Type, got.Meta, test.wantName, test.wantType, test.wantMeta)
+			continue
+		}
+		for k, v := range test.wantMeta {
+			if got.Meta[k] != v {
+				t.Errorf("%s: meta %s = %q, want %q", test.name, k, got.Meta[k], v)
+			}
+		}
+	}
+
+	tt := newTestTracker(t, Entry{Name: "Start", Timestamp: at("09:00")}, Entry{Name: "Fix", Timestamp: at("10:00")})
+	if err := tt.editEntry(1, "@ticket=3", ""); err == nil {
+		t.Error("editEntry accepted a name that is only metadata")
+	}
+}
+
+func TestMigrateConfigEditor(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{"editor": "vi", "week_start": "sunday"}`, ""},
+		{`{"editor": "vi", "config_version": 1}`, "vi"},
+		{`{"editor": "nano"}`, "nano"},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		t.Setenv("TT_CONFIG_DIR", dir)
+		path := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(path, []byte(test.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			tt := &TimeTracker{}
+			if err := tt.loadConfig(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.config.Editor != test.want || tt.config.ConfigVersion != configVersion {
+				t.Errorf("%s, load %d: editor %q version %d, want %q version %d", test.config, i+1, tt.config.Editor, tt.config.ConfigVersion, test.want, configVersion)
+			}
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(test.config, "sunday") && !strings.Contains(string(data), "sunday") {
+			t.Errorf("%s: migrating lost week_start:\n%s", test.config, data)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor, env string
+		want        []string
+	}{
+		{"code --wait", "nano", []string{"code", "--wait", "entries.json"}},
+		{"", "nano", []string{"nano", "entries.json"}},
+		{"vi", "nano", []string{"vi", "entries.json"}},
+		{"", "", []string{"vi", "entries.json"}},
+	}
+	for _, test := range tests {
+		t.Setenv("EDITOR", test.env)
+		cmd := Config{Editor: test.editor}.editorCommand("entries.json")
+		if strings.Join(cmd.Args, " ") != strings.Join(test.want, " ") {
+			t.Errorf("editor %q with $EDITOR %q runs %q, want %q", test.editor, test.env, cmd.Args, test.want)
+		}
+	}
+}
+
+func TestReportColumnsConfigError(t *testing.T) {
+	tests := []struct {
+		columns string
+		want    []string
+	}{
+		{`[{"name": "time", "width": 10}, {"name": "activity", "width": "auto"}]`, nil},
+		{`[{"name": "time", "width": 10}, {"name": "notes", "width": 20}]`, []string{`unknown column "notes"`}},
+		{`[{"name": "activity", "width": "wide"}, {"name": "type", "width": -3}]`, []string{`"activity" must be`, `"type" must be`}},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		t.Setenv("TT_CONFIG_DIR", dir)
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"report_columns": `+test.columns+`}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tt := &TimeTracker{}
+		err := tt.loadConfig()
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.columns, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: no error, want one mentioning %q", test.columns, test.want)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q doesn't mention %q", test.columns, err, want)
+			}
+		}
+	}
+}
+
+func TestPairedIntervalsFollowEntries(t *testing.T) {
+	tt := newTestTracker(t, Entry{Timestamp: at("09:00"), Name: "Build", Action: actionIn})
+	if _, running := tt.clockIntervals(); len(running) != 1 {
+		t.Fatalf("running = %d clock-ins, want 1", len(running))
+	}
+	if err := tt.addEntry("out", Entry{Timestamp: at("10:00"), Name: "Build", Action: actionOut}); err != nil {
+		t.Fatal(err)
+	}
+	closed, running := tt.clockIntervals()
+	if len(closed) != 1 || len(running) != 0 {
+		t.Errorf("after clocking out: %d closed, %d running; want 1, 0", len(closed), len(running))
+	}
+}
+
+func TestDayActivitiesFollowEntries(t *testing.T) {
+	tt := newTestTracker(t, Entry{Timestamp: at("09:00"), Name: "Start"}, Entry{Timestamp: at("10:00"), Name: "Email"})
+	if got := len(tt.getDayActivities(at("12:00"))); got != 1 {
+		t.Fatalf("%d activities, want 1", got)
+	}
+	if err := tt.addEntry("add", Entry{Timestamp: at("11:00"), Name: "Call"}); err != nil {
+		t.Fatal(err)
+	}
+	activities := tt.getDayActivities(at("12:00"))
+	if len(activities) != 2 {
+		t.Fatalf("%d activities after adding one, want 2", len(activities))
+	}
+	activities[0].Name = "Changed"
+	if tt.getDayActivities(at("12:00"))[0].Name != "Email" {
+		t.Error("changing a returned activity changed the cached day")
+	}
+}
+
+func TestSummaryProjectsCountOverlapOnce(t *testing.T) {
+	tt := newTestTracker(t)
+	activities := []Activity{
+		{Name: "Build", Project: "Acme", Type: Work, Start: at("09:00"), End: at("10:00"), Duration: time.Hour},
+		{Name: "Deploy", Project: "Acme", Type: Work, Start: at("09:30"), End: at("10:30"), Duration: time.Hour, Overlap: 30 * time.Minute, Parallel: true},
+		{Name: "Email", Type: Work, Start: at("10:30"), End: at("11:00"), Duration: 30 * time.Minute},
+	}
+	summary := tt.generateSummary(activities)
+	show := tt.config.display()
+	want := map[string]time.Duration{"Acme:": 90 * time.Minute, "General:": 30 * time.Minute}
+	for _, line := range strings.Split(summary, "\n") {
+		fields := strings.Fields(line)
+		if d, ok := want[strings.Join(fields[:min(len(fields), 1)], "")]; ok {
+			if fields[1] != show.duration(d) {
+				t.Errorf("%q, want %s", line, show.duration(d))
+			}
+			delete(want, fields[0])
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("summary has no line for %v:\n%s", want, summary)
+	}
+}
+
+func TestWriteHours(t *testing.T) {
+	var entries []Entry
+	for day := 0; day < 5; day++ {
+		entries = append(entries,
+			Entry{Timestamp: at("09:00", day), Name: "Start"},
+			Entry{Timestamp: at("16:28", day), Name: "Work"},
+		)
+	}
+	tt := newTestTracker(t, entries...)
+	tests := []struct {
+		roundTo time.Duration
+		want    string
+	}{
+		{0, "37.33\n"},
+		{15 * time.Minute, "37.5\n"},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		writeHours(&out, tt, at("00:00"), at("00:00", 7), reportOptions{RoundTo: test.roundTo})
+		if out.String() != test.want {
+			t.Errorf("round to %s: wrote %q, want %q", test.roundTo, out.String(), test.want)
+		}
+	}
+}
+
+// withCLIFlags swaps in a flag set with tt's flag names for the length of
+// the test, since main defines them
+func withCLIFlags(t *testing.T) {
+	t.Helper()
+	saved := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = saved })
+	flag.CommandLine = flag.NewFlagSet("tt", flag.ContinueOnError)
+	for _, name := range strings.Fields("a s r x h e note c last this force normalize dry-run no-sort no-trim no-dedupe collapse " +
+		"import-calendar import-summary import export project git rename date anonymize open close in out edit project-stats " +
+		"find type reflect profiles p data-file config-dir meta no-emoji i only-work billable merge archive q audit check " +
+		"sprint from to u pace hours t ago where") {
+		flag.Bool(name, false, "")
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	withCLIFlags(t)
+	tests := []struct {
+		input, want string
+	}{
+		{"reprt", "-r"},
+		{"-exprt", "-export"},
+		{"stat", "-s"},
+		{"--profils", "-profiles"},
+		{"-z", ""}, // As close to every one-letter flag
+		{"sa", ""}, // As close to -s as to -a
+		{"zzzzzz", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := suggestCommand(test.input); got != test.want {
+			t.Errorf("suggestCommand(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	messages := []struct {
+		got, want string
+	}{
+		{unknownCommandMessage("reprt"), "Unknown command 'reprt' — run 'tt -h' for usage\nDid you mean '-r'?"},
+		{unknownCommandMessage("sa"), "Unknown command 'sa' — run 'tt -h' for usage"},
+		{flagErrorMessage(errors.New("flag provided but not defined: -z")), "Unknown flag '-z' — run 'tt -h' for usage"},
+	}
+	for _, test := range messages {
+		if test.got != test.want {
+			t.Errorf("message = %q, want %q", test.got, test.want)
+		}
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	fixture := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Sprint plan",
+		" ning",
+		"DTSTART:20250310T100000",
+		"DTEND:20250310T110000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Call\\, Berlin",
+		"DTSTART;TZID=Europe/Berlin:20250310T120000",
+		"DTEND;TZID=Europe/Berlin:20250310T123000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Offsite",
+		"DTSTART;VALUE=DATE:20250310",
+		"DTEND;VALUE=DATE:20250311",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Retro",
+		"DTSTART:20250311T160000",
+		"DTEND:20250311T170000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := parseICS(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("parseICS: %v", err)
+	}
+	want := []calendarEvent{
+		{Summary: "Sprint planning", Start: at("10:00"), End: at("11:00")},
+		{Summary: "Call, Berlin", Start: time.Date(2025, 3, 10, 12, 0, 0, 0, berlin), End: time.Date(2025, 3, 10, 12, 30, 0, 0, berlin)},
+		{Summary: "Offsite", Start: at("00:00"), End: at("00:00", 1), AllDay: true},
+		{Summary: "Retro", Start: at("16:00", 1), End: at("17:00", 1)},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("parseICS returned %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		w := want[i]
+		if event.Summary != w.Summary || !event.Start.Equal(w.Start) || !event.End.Equal(w.End) || event.AllDay != w.AllDay {
+			t.Errorf("event %d = %+v, want %+v", i, event, w)
+		}
+	}
+
+	// Only the timed events on the day become entries, logged at their end
+	tt := newTestTracker(t)
+	entries := tt.calendarEntries(events, at("00:00"))
+	wantEntries := []Entry{
+		{Timestamp: at("11:00"), Name: "Meeting: Sprint planning"},
+		{Timestamp: want[1].End, Name: "Meeting: Call, Berlin"},
+	}
+	sortEntries(wantEntries)
+	if len(entries) != len(wantEntries) {
+		t.Fatalf("calendarEntries returned %d entries, want %d: %+v", len(entries), len(wantEntries), entries)
+	}
+	for i, entry := range entries {
+		if entry.Name != wantEntries[i].Name || !entry.Timestamp.Equal(wantEntries[i].Timestamp) {
+			t.Errorf("entry %d = %s %q, want %s %q", i, entry.Timestamp, entry.Name, wantEntries[i].Timestamp, wantEntries[i].Name)
+		}
+	}
+}
+
+func TestAnonymizeActivities(t *testing.T) {
+	activities := []Activity{
+		{Name: "Acme: Design", Project: "Acme", Task: "Design", Start: at("09:00"), End: at("10:00"), Duration: time.Hour,
+			Comment: "mockups for Bob", Meta: map[string]string{"ticket": "ACME-1"}, Tags: []string{"client"}},
+		{Name: "Lunch **", Task: "Lunch", Type: Break, Start: at("10:00"), End: at("10:30"), Duration: 30 * time.Minute},
+		{Name: "Initech: Design", Project: "Initech", Task: "Design", Start: at("10:30"), End: at("11:00"), Duration: 30 * time.Minute},
+		{Name: "Acme: Review", Project: "Acme", Task: "Review", Start: at("11:00"), End: at("12:00"), Duration: time.Hour},
+	}
+	anonymized := anonymizeActivities(activities)
+	if len(anonymized) != len(activities) {
+		t.Fatalf("anonymizeActivities returned %d activities, want %d", len(anonymized), len(activities))
+	}
+
+	wantProjects := []string{"Project A", "", "Project B", "Project A"}
+	for i, activity := range anonymized {
+		original := activities[i]
+		if activity.Project != wantProjects[i] {
+			t.Errorf("activity %d project = %q, want %q", i, activity.Project, wantProjects[i])
+		}
+		if strings.Contains(activity.Name, original.Task) || (original.Project != "" && strings.Contains(activity.Name, original.Project)) {
+			t.Errorf("activity %d name %q still names %q", i, activity.Name, original.Name)
+		}
+		if activity.Comment != "" || activity.Meta != nil || activity.Tags != nil {
+			t.Errorf("activity %d kept comment %q, meta %v or tags %v", i, activity.Comment, activity.Meta, activity.Tags)
+		}
+		if !activity.Start.Equal(original.Start) || !activity.End.Equal(original.End) || activity.Duration != original.Duration || activity.Type != original.Type {
+			t.Errorf("activity %d = %s-%s %s %v, want %s-%s %s %v", i, activity.Start, activity.End, activity.Duration, activity.Type,
+				original.Start, original.End, original.Duration, original.Type)
+		}
+	}
+	if anonymized[0].Task != anonymized[2].Task {
+		t.Errorf("the same task became %q and %q", anonymized[0].Task, anonymized[2].Task)
+	}
+	if activities[0].Comment == "" || activities[0].Project != "Acme" {
+		t.Errorf("anonymizeActivities changed its input: %+v", activities[0])
+	}
+}
+
+func TestRunMerge(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Timestamp: at("10:00"), Name: "Acme: Design"},
+		Entry{Timestamp: at("11:00"), Name: "Email"},
+	)
+	if err := tt.saveEntries(); err != nil {
+		t.Fatalf("saveEntries: %v", err)
+	}
+	other := filepath.Join(t.TempDir(), "laptop.json")
+	if err := writeEntriesFile(other, []Entry{
+		{Timestamp: at("10:00"), Name: "Acme: Design"},                // Already present
+		{Timestamp: at("11:00").Add(30 * time.Second), Name: "Email"}, // Near an existing entry
+		{Timestamp: at("12:00"), Name: "Acme: Review"},
+		{Timestamp: at("12:00"), Name: "Acme: Review"}, // Twice in the other file
+	}); err != nil {
+		t.Fatalf("writeEntriesFile: %v", err)
+	}
+
+	var err error
+	output := captureStdout(t, func() { err = runMerge(tt, other) })
+	if err != nil {
+		t.Fatalf("runMerge: %v", err)
+	}
+	for _, want := range []string{
+		"Added:   2\n",
+		"Skipped: 2 (already present)\n",
+		"Warning: 1 added entries are within a minute of an existing entry with the same name:\n",
+		at("11:00").Add(30*time.Second).Format("2006-01-02 15:04:05") + "  Email\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("runMerge output is missing %q:\n%s", want, output)
+		}
+	}
+
+	merged, err := readEntriesFile(tt.config.DataFile)
+	if err != nil {
+		t.Fatalf("readEntriesFile: %v", err)
+	}
+	if len(merged) != 4 {
+		t.Errorf("data file has %d entries after merging, want 4: %+v", len(merged), merged)
+	}
+	if !entriesSorted(merged) {
+		t.Errorf("merged entries are out of order: %+v", merged)
+	}
+}
+
+func TestParseEntriesCSV(t *testing.T) {
+	input := strings.Join([]string{
+		"timestamp,name,comment",
+		"2025-03-10 09:00,Acme: Design",
+		"2025-03-10 10:00,Email,\"inbox, then calendar\"",
+		"yesterday,Lunch **",
+		"2025-03-10 11:00",
+		"2025-03-10 12:00,Acme: Review @ticket=ACME-1",
+	}, "\n")
+	entries, problems := parseEntriesCSV(strings.NewReader(input))
+
+	want := []Entry{
+		{Timestamp: at("09:00"), Name: "Acme: Design"},
+		{Timestamp: at("10:00"), Name: "Email", Comment: "inbox, then calendar"},
+		{Timestamp: at("12:00"), Name: "Acme: Review", Meta: map[string]string{"ticket": "ACME-1"}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("parseEntriesCSV returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		w := want[i]
+		if !entry.Timestamp.Equal(w.Timestamp) || entry.Name != w.Name || entry.Comment != w.Comment || entry.Meta["ticket"] != w.Meta["ticket"] {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, w)
+		}
+	}
+
+	wantProblems := []string{
+		`line 4: invalid timestamp "yesterday" (use RFC3339 or YYYY-MM-DD HH:MM)`,
+		"line 5: want timestamp,name,comment",
+	}
+	if strings.Join(problems, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("problems = %q, want %q", problems, wantProblems)
+	}
+
+	// Importing skips rows already in the data file
+	tt := newTestTracker(t, Entry{Timestamp: at("09:00"), Name: "Acme: Design"})
+	path := filepath.Join(t.TempDir(), "entries.csv")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	output := captureStdout(t, func() { err = runCSVImport(tt, path) })
+	if err != nil {
+		t.Fatalf("runCSVImport: %v", err)
+	}
+	for _, want := range []string{"Added:   2\n", "Skipped: 1 (already present)\n", "Invalid: 2 (not imported)\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("runCSVImport output is missing %q:\n%s", want, output)
+		}
+	}
+	if len(tt.entries) != 3 {
+		t.Errorf("tracker has %d entries after importing, want 3: %+v", len(tt.entries), tt.entries)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	name, meta := splitMeta("Acme: Fix login @ticket=ACME-42 @estimate=2h")
+	if name != "Acme: Fix login" || meta["ticket"] != "ACME-42" || meta["estimate"] != "2h" || len(meta) != 2 {
+		t.Fatalf("splitMeta = %q, %v", name, meta)
+	}
+	if got, want := formatMeta(meta), "@estimate=2h @ticket=ACME-42"; got != want {
+		t.Errorf("formatMeta = %q, want %q", got, want)
+	}
+	if _, again := splitMeta(formatMeta(meta)); again["ticket"] != "ACME-42" || again["estimate"] != "2h" || len(again) != 2 {
+		t.Errorf("splitMeta(formatMeta(meta)) = %v, want %v", again, meta)
+	}
+	// Only @-prefixed tokens are metadata
+	if name, meta := splitMeta("Compare a=b @=c"); name != "Compare a=b @=c" || meta != nil {
+		t.Errorf("splitMeta = %q, %v, want no metadata", name, meta)
+	}
+
+	where, err := parseMetaPairs("ticket=ACME-42")
+	if err != nil {
+		t.Fatalf("parseMetaPairs: %v", err)
+	}
+	opts := reportOptions{Where: where}
+	tests := []struct {
+		meta map[string]string
+		want bool
+	}{
+		{meta, true},
+		{map[string]string{"ticket": "ACME-7"}, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := opts.keep(Activity{Name: "Acme: Fix login", Meta: test.meta}); got != test.want {
+			t.Errorf("keep with -where ticket=ACME-42 and meta %v = %v, want %v", test.meta, got, test.want)
+		}
+	}
+}
+
+func TestSplitDayTotals(t *testing.T) {
+	work := func(start, end time.Time) Activity {
+		return Activity{Name: "Acme: Design", Start: start, End: end, Duration: end.Sub(start)}
+	}
+	tests := []struct {
+		name       string
+		afternoon  int
+		evening    int
+		activities []Activity
+		want       [3]time.Duration
+	}{
+		{"spanning noon", 12, 17, []Activity{work(at("11:30"), at("12:30"))}, [3]time.Duration{30 * time.Minute, 30 * time.Minute, 0}},
+		{"spanning evening", 12, 17, []Activity{work(at("16:00"), at("18:00"))}, [3]time.Duration{0, time.Hour, time.Hour}},
+		{"across midnight", 12, 17, []Activity{work(at("23:00"), at("01:00", 1))}, [3]time.Duration{time.Hour, 0, time.Hour}},
+		{"moved boundaries", 13, 18, []Activity{work(at("11:30"), at("12:30"))}, [3]time.Duration{time.Hour, 0, 0}},
+		{"breaks left out", 12, 17, []Activity{
+			work(at("09:00"), at("12:00")),
+			{Name: "Lunch **", Type: Break, Start: at("12:00"), End: at("13:00"), Duration: time.Hour},
+		}, [3]time.Duration{3 * time.Hour, 0, 0}},
+	}
+	for _, test := range tests {
+		tt := newTestTracker(t)
+		tt.config.AfternoonStartHour, tt.config.EveningStartHour = test.afternoon, test.evening
+		if got := tt.splitDayTotals(test.activities); got != test.want {
+			t.Errorf("%s: splitDayTotals = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOvertimeSplit(t *testing.T) {
+	// day is hours of work starting at 09:00, days after the Monday
+	day := func(hours float64, days int) Activity {
+		return Activity{Name: "Acme: Design", Start: at("09:00", days), End: at("09:00", days).Add(hoursDuration(hours)), Duration: hoursDuration(hours)}
+	}
+	week := func(t time.Time) string { return dayKey(startOfWeek(t, time.Monday)) }
+	tests := []struct {
+		name                      string
+		activities                []Activity
+		threshold                 time.Duration
+		period                    func(time.Time) string
+		wantRegular, wantOvertime time.Duration
+	}{
+		{"daily below", []Activity{day(7, 0)}, 8 * time.Hour, dayKey, 7 * time.Hour, 0},
+		{"daily at", []Activity{day(8, 0)}, 8 * time.Hour, dayKey, 8 * time.Hour, 0},
+		{"daily above", []Activity{day(9.5, 0)}, 8 * time.Hour, dayKey, 8 * time.Hour, 90 * time.Minute},
+		{"daily per day", []Activity{day(9, 0), day(6, 1)}, 8 * time.Hour, dayKey, 14 * time.Hour, time.Hour},
+		{"weekly below", []Activity{day(8, 0), day(8, 1), day(8, 2)}, 40 * time.Hour, week, 24 * time.Hour, 0},
+		{"weekly at", []Activity{day(8, 0), day(8, 1), day(8, 2), day(8, 3), day(8, 4)}, 40 * time.Hour, week, 40 * time.Hour, 0},
+		{"weekly above", []Activity{day(9, 0), day(9, 1), day(9, 2), day(9, 3), day(7.5, 4)}, 40 * time.Hour, week, 40 * time.Hour, 210 * time.Minute},
+		{"weekly per week", []Activity{day(45, 0), day(10, 7)}, 40 * time.Hour, week, 50 * time.Hour, 5 * time.Hour},
+		{"breaks left out", []Activity{day(8, 0), {Type: Break, Start: at("17:00"), End: at("18:00"), Duration: time.Hour}}, 8 * time.Hour, dayKey, 8 * time.Hour, 0},
+	}
+	for _, test := range tests {
+		regular, overtime := overtimeSplit(test.activities, test.threshold, test.period)
+		if regular != test.wantRegular || overtime != test.wantOvertime {
+			t.Errorf("%s: overtimeSplit = %s regular, %s overtime, want %s, %s", test.name, regular, overtime, test.wantRegular, test.wantOvertime)
+		}
+	}
+}
+
+func TestSummaryEntries(t *testing.T) {
+	rows := []summaryRow{
+		{Day: at("00:00"), Hours: 3, Project: "Acme"},
+		{Day: at("00:00"), Hours: 1.5, Project: "Initech"},
+		{Day: at("00:00", 1), Hours: 7.25},
+	}
+	want := []Entry{
+		{Timestamp: at("09:00"), Name: "Start"},
+		{Timestamp: at("12:00"), Name: "Acme: Imported total"},
+		{Timestamp: at("13:30"), Name: "Initech: Imported total"},
+		{Timestamp: at("09:00", 1), Name: "Start"},
+		{Timestamp: at("16:15", 1), Name: "Imported total"},
+	}
+	entries := summaryEntries(rows)
+	if len(entries) != len(want) {
+		t.Fatalf("summaryEntries returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if !entry.Timestamp.Equal(want[i].Timestamp) || entry.Name != want[i].Name || entry.Meta["source"] != "summary" {
+			t.Errorf("entry %d = %s %q %v, want %s %q @source=summary", i, entry.Timestamp.Format("Mon 15:04"), entry.Name, entry.Meta,
+				want[i].Timestamp.Format("Mon 15:04"), want[i].Name)
+		}
+	}
+
+	// The entries read back as each row's hours against its project
+	tt := newTestTracker(t, entries...)
+	projects := computeProjects(tt.activitiesIn(at("00:00"), at("00:00", 1)))
+	if projects["Acme"] != 3*time.Hour || projects["Initech"] != 90*time.Minute {
+		t.Errorf("projects = %v, want Acme 3h and Initech 1h30", projects)
+	}
+}
+
+func TestDurationSharesOfShownRows(t *testing.T) {
+	tt := newTestTracker(t,
+		Entry{Timestamp: at("09:00"), Name: "Start"},
+		Entry{Timestamp: at("10:00"), Name: "Acme: Design"},
+		Entry{Timestamp: at("11:00"), Name: "Lunch **"},
+		Entry{Timestamp: at("12:00"), Name: "Email"},
+	)
+	if err := tt.saveEntries(); err != nil {
+		t.Fatalf("saveEntries: %v", err)
+	}
+	m := initialModel()
+	m.reportDay = at("00:00")
+	m.reportTypes[Break] = false
+	m.durationMode = "percent"
+	m.updateReportData()
+
+	if got := m.table.Columns()[1].Title; got != "% of shown" {
+		t.Errorf("duration column title = %q, want %q", got, "% of shown")
+	}
+	rows := m.table.Rows()
+	if len(rows) != 2 {
+		t.Fatalf("table has %d rows, want the 2 work rows: %v", len(rows), rows)
+	}
+	for _, row := range rows {
+		if row[1] != "50%" {
+			t.Errorf("row %v shows %s, want 50%% of the rows shown", row, row[1])
+		}
+	}
+
+	m.durationMode = ""
+	m.updateReportData()
+	if got := m.table.Columns()[1].Title; got != "Duration" {
+		t.Errorf("duration column title = %q, want %q", got, "Duration")
+	}
+}